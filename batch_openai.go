@@ -0,0 +1,296 @@
+package docqa
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BatchRequest is a single document to answer questions about, as part of a batch.
+type BatchRequest struct {
+	// ID identifies the request within the batch, and must be unique.
+	ID        string
+	Document  string
+	Questions map[string]Question
+	Protocol  Protocol
+}
+
+// BatchResult is the outcome of a single [BatchRequest].
+type BatchResult struct {
+	Answers map[string][]Entity
+	Usage   LLMUsage
+	Err     error
+}
+
+// OpenAIBatchStatus describes the state of a batch on the OpenAI Batch API.
+type OpenAIBatchStatus struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+}
+
+// Finished reports whether the batch will not make any more progress, successfully or otherwise.
+func (s OpenAIBatchStatus) Finished() bool {
+	switch s.Status {
+	case "completed", "failed", "expired", "cancelled":
+		return true
+	default:
+		return false
+	}
+}
+
+// OpenAIBatcher extracts answers from many documents at once, at a reduced cost, using the OpenAI Batch API.
+// Building the request file ([OpenAIBatcher.WriteRequests]) and parsing the result file ([OpenAIBatcher.ParseResults])
+// never touch the network, so they can be used with files moved around by other means.
+type OpenAIBatcher struct {
+	client *openAIClient
+}
+
+// NewOpenAIBatcher creates an [OpenAIBatcher] that sends requests to the given model.
+// The [ClientOption]s are the same as for [NewOpenAIClient].
+func NewOpenAIBatcher(key, model string, opts ...ClientOption) *OpenAIBatcher {
+	return &OpenAIBatcher{
+		client: newOpenAIClient(key, model, opts),
+	}
+}
+
+// WriteRequests writes the `.jsonl` input file for a batch, with one chat completion request per [BatchRequest].
+func (b *OpenAIBatcher) WriteRequests(w io.Writer, requests []BatchRequest) error {
+	seen := make(map[string]bool)
+	enc := json.NewEncoder(w)
+	for _, r := range requests {
+		if seen[r.ID] {
+			return fmt.Errorf("duplicate batch request id %s", r.ID)
+		}
+		seen[r.ID] = true
+		if err := validateQuestions(r.Questions); err != nil {
+			return fmt.Errorf("batch request %s: %w", r.ID, err)
+		}
+		line := map[string]any{
+			"custom_id": r.ID,
+			"method":    "POST",
+			"url":       b.endpoint(),
+			"body":      b.client.requestBody(r.Protocol.SystemPrompt(r.Questions), userMessages(protocolUserPrompt(r.Protocol, r.Document)), r.Protocol.Schema(r.Questions)),
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseResults reads a batch output or error file, parsing each response with the [Protocol] of the matching request.
+// Failures of individual requests are reported in [BatchResult.Err], and requests with no result in the file are omitted.
+func (b *OpenAIBatcher) ParseResults(r io.Reader, requests []BatchRequest) (map[string]BatchResult, error) {
+	byID := make(map[string]BatchRequest, len(requests))
+	for _, req := range requests {
+		byID[req.ID] = req
+	}
+	results := make(map[string]BatchResult)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line openAIBatchLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("failed to parse batch result line: %w", err)
+		}
+		req, ok := byID[line.CustomID]
+		if !ok {
+			return nil, fmt.Errorf("batch result for unknown request id %s", line.CustomID)
+		}
+		results[line.CustomID] = line.parse(req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+type openAIBatchLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// parse converts a line of a batch output file into the result of the request it answers.
+func (line openAIBatchLine) parse(req BatchRequest) BatchResult {
+	if line.Error != nil {
+		return BatchResult{Err: fmt.Errorf("batch request failed (%s): %s", line.Error.Code, line.Error.Message)}
+	}
+	if line.Response == nil {
+		return BatchResult{Err: fmt.Errorf("batch result had neither a response nor an error")}
+	}
+	if line.Response.StatusCode < 200 || line.Response.StatusCode >= 300 {
+		return BatchResult{Err: &HTTPStatusError{StatusCode: line.Response.StatusCode, Body: string(line.Response.Body)}}
+	}
+	resp, usage, err := parseOpenAIResponse(line.Response.Body)
+	if err != nil {
+		return BatchResult{Usage: usage, Err: err}
+	}
+	answers, err := protocolParseResponse(req.Protocol, resp, req.Document)
+	if err == nil {
+		err = ValidateCardinality(req.Questions, answers)
+	}
+	if err != nil {
+		return BatchResult{Usage: usage, Err: newMalformedResponseError(resp, usage, err)}
+	}
+	return BatchResult{Answers: answers, Usage: usage}
+}
+
+// endpoint is the path of the chat completions endpoint that the batch requests are for, see [WithBatchEndpoint].
+func (b *OpenAIBatcher) endpoint() string {
+	if b.client.cfg.batchEndpoint != "" {
+		return b.client.cfg.batchEndpoint
+	}
+	base, err := url.Parse(b.client.cfg.baseURL)
+	if err != nil {
+		return "/chat/completions"
+	}
+	return strings.TrimRight(base.Path, "/") + "/chat/completions"
+}
+
+// Submit uploads a batch input file (as written by [OpenAIBatcher.WriteRequests]) and starts a batch for it.
+func (b *OpenAIBatcher) Submit(ctx context.Context, input io.Reader) (OpenAIBatchStatus, error) {
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	if err := mw.WriteField("purpose", "batch"); err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	fw, err := mw.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	if _, err := io.Copy(fw, input); err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	if err := mw.Close(); err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	cfg := b.client.cfg
+	req, err := cfg.newRawRequest(ctx, "POST", "/files", b.client.key, &form, mw.FormDataContentType())
+	if err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	respBody, err := cfg.do(req)
+	if err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	file := struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.Unmarshal(respBody, &file); err != nil || file.ID == "" {
+		return OpenAIBatchStatus{}, fmt.Errorf("failed to parse file upload response: %s", string(respBody))
+	}
+
+	req, err = cfg.newRequest(ctx, "/batches", b.client.key, map[string]any{
+		"input_file_id":     file.ID,
+		"endpoint":          b.endpoint(),
+		"completion_window": "24h",
+	})
+	if err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	return b.doStatus(req)
+}
+
+// Status fetches the current status of a batch.
+func (b *OpenAIBatcher) Status(ctx context.Context, batchID string) (OpenAIBatchStatus, error) {
+	req, err := b.client.cfg.newRawRequest(ctx, "GET", "/batches/"+url.PathEscape(batchID), b.client.key, nil, "")
+	if err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	return b.doStatus(req)
+}
+
+// Wait polls the status of a batch every pollInterval until it has finished.
+func (b *OpenAIBatcher) Wait(ctx context.Context, batchID string, pollInterval time.Duration) (OpenAIBatchStatus, error) {
+	for {
+		status, err := b.Status(ctx, batchID)
+		if err != nil {
+			return OpenAIBatchStatus{}, err
+		}
+		if status.Finished() {
+			return status, nil
+		}
+		if err := sleepContext(ctx, pollInterval); err != nil {
+			return OpenAIBatchStatus{}, err
+		}
+	}
+}
+
+// Download fetches the content of a file, such as the output file of a batch.
+func (b *OpenAIBatcher) Download(ctx context.Context, fileID string) ([]byte, error) {
+	req, err := b.client.cfg.newRawRequest(ctx, "GET", "/files/"+url.PathEscape(fileID)+"/content", b.client.key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return b.client.cfg.do(req)
+}
+
+// Run builds, submits, and waits for a batch, then downloads and parses its results.
+// This may take up to the 24 hour completion window of the Batch API.
+func (b *OpenAIBatcher) Run(ctx context.Context, requests []BatchRequest, pollInterval time.Duration) (map[string]BatchResult, error) {
+	var input bytes.Buffer
+	if err := b.WriteRequests(&input, requests); err != nil {
+		return nil, err
+	}
+	status, err := b.Submit(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+	status, err = b.Wait(ctx, status.ID, pollInterval)
+	if err != nil {
+		return nil, err
+	}
+	if status.Status != "completed" {
+		return nil, fmt.Errorf("batch %s finished with status %s", status.ID, status.Status)
+	}
+	results := make(map[string]BatchResult)
+	for _, fileID := range []string{status.OutputFileID, status.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		content, err := b.Download(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		fileResults, err := b.ParseResults(bytes.NewReader(content), requests)
+		if err != nil {
+			return nil, err
+		}
+		for id, result := range fileResults {
+			results[id] = result
+		}
+	}
+	return results, nil
+}
+
+func (b *OpenAIBatcher) doStatus(req *http.Request) (OpenAIBatchStatus, error) {
+	respBody, err := b.client.cfg.do(req)
+	if err != nil {
+		return OpenAIBatchStatus{}, err
+	}
+	var status OpenAIBatchStatus
+	if err := json.Unmarshal(respBody, &status); err != nil || status.ID == "" {
+		return OpenAIBatchStatus{}, fmt.Errorf("failed to parse batch response: %s", string(respBody))
+	}
+	return status, nil
+}
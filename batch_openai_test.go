@@ -0,0 +1,234 @@
+package docqa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestBatchRequests() []BatchRequest {
+	protocol := newTestTextProtocol()
+	questions := map[string]Question{
+		"title": {Question: "What is the title of the document?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1, MaxAnswers: 1},
+	}
+	requests := make([]BatchRequest, 6)
+	for i := range requests {
+		requests[i] = BatchRequest{
+			ID:        fmt.Sprintf("report-%d", i+1),
+			Document:  fmt.Sprintf("Annual Report %d", i+1),
+			Questions: questions,
+			Protocol:  protocol,
+		}
+	}
+	return requests
+}
+
+func TestOpenAIBatcherWriteRequests(t *testing.T) {
+	requests := newTestBatchRequests()[:2]
+	var buf bytes.Buffer
+	if err := NewOpenAIBatcher("key", "gpt-test").WriteRequests(&buf, requests); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(requests) {
+		t.Fatalf("expected %d lines, got %d", len(requests), len(lines))
+	}
+	for i, line := range lines {
+		var decoded map[string]any
+		if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+			t.Fatalf("line %d is not json: %v", i, err)
+		}
+		req := requests[i]
+		if decoded["custom_id"] != req.ID || decoded["method"] != "POST" || decoded["url"] != "/v1/chat/completions" {
+			t.Errorf("line %d has the wrong custom_id, method or url: %s", i, line)
+		}
+		if got := jsonPath(t, decoded, "body", "model"); got != "gpt-test" {
+			t.Errorf("line %d has model %v", i, got)
+		}
+		if got := jsonPath(t, decoded, "body", "messages", 0, "content"); got != req.Protocol.SystemPrompt(req.Questions) {
+			t.Errorf("line %d does not have the system prompt of its request", i)
+		}
+		if got := jsonPath(t, decoded, "body", "messages", 1, "content"); got != req.Document {
+			t.Errorf("line %d has user prompt %v, expected %q", i, got, req.Document)
+		}
+		schema := jsonPath(t, decoded, "body", "response_format", "json_schema", "schema")
+		if got := jsonPath(t, schema, "properties", "title", "maxItems"); got != 1.0 {
+			t.Errorf("line %d does not have the schema of its questions, got maxItems %v", i, got)
+		}
+	}
+
+	t.Run("duplicate ids", func(t *testing.T) {
+		err := NewOpenAIBatcher("key", "gpt-test").WriteRequests(&bytes.Buffer{}, []BatchRequest{requests[0], requests[0]})
+		if err == nil || !strings.Contains(err.Error(), "duplicate batch request id report-1") {
+			t.Errorf("expected a duplicate id error, got %v", err)
+		}
+	})
+}
+
+func TestOpenAIBatcherEndpoint(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []ClientOption
+		want string
+	}{
+		{name: "openai", want: "/v1/chat/completions"},
+		{name: "proxy with a path", opts: []ClientOption{WithBaseURL("https://proxy.example/openai/v1/")}, want: "/openai/v1/chat/completions"},
+		{name: "server without a path", opts: []ClientOption{WithBaseURL("http://localhost:4000")}, want: "/chat/completions"},
+		{
+			name: "azure",
+			opts: []ClientOption{WithBaseURL("https://example.openai.azure.com/openai"), WithAPIVersion("2024-10-21"), WithBatchEndpoint("/chat/completions")},
+			want: "/chat/completions",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewOpenAIBatcher("key", "gpt-test", tc.opts...).WriteRequests(&buf, newTestBatchRequests()[:1]); err != nil {
+				t.Fatal(err)
+			}
+			var decoded map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded["url"] != tc.want {
+				t.Errorf("expected url %s, got %v", tc.want, decoded["url"])
+			}
+		})
+	}
+
+	t.Run("submit", func(t *testing.T) {
+		var endpoint any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/openai/batches" {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("batch request was not json: %v", err)
+				}
+				endpoint = body["endpoint"]
+			}
+			io.WriteString(w, `{"id":"batch-1","status":"validating"}`)
+		}))
+		defer server.Close()
+		batcher := NewOpenAIBatcher("key", "gpt-test", WithBaseURL(server.URL+"/openai"), WithBatchEndpoint("/chat/completions"))
+		if _, err := batcher.Submit(context.Background(), strings.NewReader("{}\n")); err != nil {
+			t.Fatal(err)
+		}
+		if endpoint != "/chat/completions" {
+			t.Errorf("expected the batch to be created for /chat/completions, got %v", endpoint)
+		}
+	})
+}
+
+func TestOpenAIBatcherParseResults(t *testing.T) {
+	requests := newTestBatchRequests()
+	batcher := NewOpenAIBatcher("key", "gpt-test")
+	results := make(map[string]BatchResult)
+	for _, path := range []string{"testdata/openai_batch_output.jsonl", "testdata/openai_batch_errors.jsonl"} {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileResults, err := batcher.ParseResults(f, requests)
+		f.Close()
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", path, err)
+		}
+		for id, result := range fileResults {
+			results[id] = result
+		}
+	}
+
+	cases := []struct {
+		id      string
+		answers map[string][]Entity
+		usage   LLMUsage
+		check   func(t *testing.T, err error)
+	}{
+		{
+			id: "report-1",
+			answers: map[string][]Entity{"title": {&testTextEntity{
+				EntityAttributes: EntityAttributes{LocalisedRange: IndefRange(), EvidenceRanges: []Range{}},
+				Text:             "Annual Report",
+			}}},
+			usage: LLMUsage{InputTokens: 100, OutputTokens: 20},
+		},
+		{
+			id:    "report-2",
+			usage: LLMUsage{InputTokens: 90, OutputTokens: 5},
+			check: func(t *testing.T, err error) {
+				var malformed *MalformedResponseError
+				if !errors.As(err, &malformed) || !strings.Contains(err.Error(), "title") {
+					t.Errorf("expected a MalformedResponseError about the title question, got %v", err)
+				}
+			},
+		},
+		{
+			id:    "report-3",
+			usage: LLMUsage{InputTokens: 80, OutputTokens: 8192},
+			check: func(t *testing.T, err error) {
+				var truncated *TruncatedError
+				if !errors.As(err, &truncated) {
+					t.Errorf("expected a TruncatedError, got %v", err)
+				}
+			},
+		},
+		{
+			id: "report-4",
+			check: func(t *testing.T, err error) {
+				var statusErr *HTTPStatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != 400 || !strings.Contains(statusErr.Body, "Invalid schema") {
+					t.Errorf("expected a 400 HTTPStatusError with the response body, got %v", err)
+				}
+			},
+		},
+		{
+			id: "report-5",
+			check: func(t *testing.T, err error) {
+				if err == nil || err.Error() != "batch request failed (batch_expired): This request could not be executed before the completion window expired." {
+					t.Errorf("expected the batch error, got %v", err)
+				}
+			},
+		},
+	}
+	if len(results) != len(cases) {
+		t.Errorf("expected %d results, as requests without a result are omitted, got %d", len(cases), len(results))
+	}
+	for _, tc := range cases {
+		t.Run(tc.id, func(t *testing.T) {
+			result, ok := results[tc.id]
+			if !ok {
+				t.Fatalf("no result for %s", tc.id)
+			}
+			if !reflect.DeepEqual(result.Answers, tc.answers) {
+				t.Errorf("expected answers %v, got %v", tc.answers, result.Answers)
+			}
+			if result.Usage != tc.usage {
+				t.Errorf("expected usage %+v, got %+v", tc.usage, result.Usage)
+			}
+			if tc.check == nil {
+				if result.Err != nil {
+					t.Errorf("expected no error, got %v", result.Err)
+				}
+				return
+			}
+			tc.check(t, result.Err)
+		})
+	}
+
+	t.Run("unknown id", func(t *testing.T) {
+		line := `{"custom_id": "other", "response": {"status_code": 200, "body": {}}}`
+		_, err := batcher.ParseResults(strings.NewReader(line), requests)
+		if err == nil || !strings.Contains(err.Error(), "unknown request id other") {
+			t.Errorf("expected an unknown id error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package docqa
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25StopWords are common words that are ignored when indexing and searching, as they say little about relevance.
+var bm25StopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"did": true, "do": true, "does": true, "for": true, "from": true, "has": true, "have": true, "how": true,
+	"in": true, "is": true, "it": true, "its": true, "of": true, "on": true, "or": true, "that": true,
+	"the": true, "this": true, "to": true, "was": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "who": true, "whom": true, "why": true, "will": true, "with": true,
+}
+
+// BM25Index is an in-process index that ranks the chunks of a document by their relevance to a query, using Okapi BM25.
+type BM25Index struct {
+	chunks       []Chunk
+	termCounts   []map[string]int
+	lengths      []int
+	avgLength    float64
+	docFrequency map[string]int
+}
+
+// BM25Result is a chunk that matched a search of a [BM25Index].
+type BM25Result struct {
+	// Index is the position of the chunk in the indexed chunks.
+	Index int
+	Chunk Chunk
+	Score float64
+}
+
+// NewBM25Index indexes the given chunks, such as those created by [ChunkDocument].
+func NewBM25Index(chunks []Chunk) *BM25Index {
+	idx := &BM25Index{
+		chunks:       chunks,
+		termCounts:   make([]map[string]int, len(chunks)),
+		lengths:      make([]int, len(chunks)),
+		docFrequency: make(map[string]int),
+	}
+	totalLength := 0
+	for i, chunk := range chunks {
+		terms := bm25Terms(chunk.Text)
+		counts := make(map[string]int)
+		for _, term := range terms {
+			counts[term]++
+		}
+		for term := range counts {
+			idx.docFrequency[term]++
+		}
+		idx.termCounts[i] = counts
+		idx.lengths[i] = len(terms)
+		totalLength += len(terms)
+	}
+	if len(chunks) > 0 {
+		idx.avgLength = float64(totalLength) / float64(len(chunks))
+	}
+	return idx
+}
+
+// Search returns the k chunks that are most relevant to the query, most relevant first.
+// Chunks with equal scores are returned in document order, so there are always min(k, number of chunks) results,
+// even if some do not match the query at all. A negative k returns no results.
+func (idx *BM25Index) Search(query string, k int) []BM25Result {
+	queryTerms := bm25Terms(query)
+	slices.Sort(queryTerms)
+	queryTerms = slices.Compact(queryTerms)
+	results := make([]BM25Result, len(idx.chunks))
+	for i, chunk := range idx.chunks {
+		results[i] = BM25Result{Index: i, Chunk: chunk, Score: idx.score(queryTerms, i)}
+	}
+	slices.SortStableFunc(results, func(a, b BM25Result) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return results[:min(max(k, 0), len(results))]
+}
+
+// score computes the BM25 score of a chunk for the (de-duplicated) query terms.
+func (idx *BM25Index) score(queryTerms []string, i int) float64 {
+	n := float64(len(idx.chunks))
+	lengthNorm := 1.0
+	if idx.avgLength > 0 {
+		lengthNorm = 1 - bm25B + bm25B*float64(idx.lengths[i])/idx.avgLength
+	}
+	score := 0.0
+	for _, term := range queryTerms {
+		tf := float64(idx.termCounts[i][term])
+		if tf == 0 {
+			continue
+		}
+		df := float64(idx.docFrequency[term])
+		idf := math.Log((n-df+0.5)/(df+0.5) + 1)
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*lengthNorm)
+	}
+	return score
+}
+
+// bm25Terms splits text into lowercase words, dropping stop words.
+func bm25Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if !bm25StopWords[w] {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
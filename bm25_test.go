@@ -0,0 +1,63 @@
+package docqa
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBM25IndexSearch(t *testing.T) {
+	chunks := []Chunk{
+		{"The cat sat on the mat.", 0},
+		{"Dogs chase cats, and the cat runs.", 24},
+		{"Invoices are due within thirty days.", 59},
+		{"Payment terms: invoice payment within thirty days.", 96},
+	}
+	cases := []struct {
+		name    string
+		chunks  []Chunk
+		query   string
+		k       int
+		indices []int
+		// matches is how many of the results should have a positive score.
+		matches int
+	}{
+		{name: "shorter chunk ranks first", chunks: chunks, query: "cat", k: 2, indices: []int{0, 1}, matches: 2},
+		{name: "case is ignored", chunks: chunks, query: "CAT", k: 2, indices: []int{0, 1}, matches: 2},
+		{name: "term frequency", chunks: chunks, query: "payment", k: 1, indices: []int{3}, matches: 1},
+		{name: "several terms", chunks: chunks, query: "When is payment due within thirty days?", k: 2, indices: []int{2, 3}, matches: 2},
+		{name: "only stop words", chunks: chunks, query: "what is the", k: 3, indices: []int{0, 1, 2}},
+		{name: "no match returns every chunk in order", chunks: chunks, query: "zebra", k: 10, indices: []int{0, 1, 2, 3}},
+		{name: "k of zero", chunks: chunks, query: "cat", k: 0, indices: []int{}},
+		{name: "negative k", chunks: chunks, query: "cat", k: -1, indices: []int{}},
+		{name: "empty index", chunks: nil, query: "cat", k: 3, indices: []int{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			results := NewBM25Index(tc.chunks).Search(tc.query, tc.k)
+			indices := make([]int, len(results))
+			for i, r := range results {
+				indices[i] = r.Index
+				if r.Chunk != tc.chunks[r.Index] {
+					t.Errorf("result %d has chunk %v, expected %v", i, r.Chunk, tc.chunks[r.Index])
+				}
+				if i > 0 && r.Score > results[i-1].Score {
+					t.Errorf("result %d scored higher than the result before it", i)
+				}
+				if matched := i < tc.matches; matched != (r.Score > 0) {
+					t.Errorf("result %d has score %v, expected it to match: %v", i, r.Score, matched)
+				}
+			}
+			if !reflect.DeepEqual(indices, tc.indices) {
+				t.Errorf("expected chunks %v, got %v", tc.indices, indices)
+			}
+		})
+	}
+
+	t.Run("repeated query terms", func(t *testing.T) {
+		idx := NewBM25Index(chunks)
+		once, twice := idx.Search("cat", 1), idx.Search("cat cat", 1)
+		if once[0].Score != twice[0].Score {
+			t.Errorf("expected repeating a query term not to change the score, got %v and %v", once[0].Score, twice[0].Score)
+		}
+	})
+}
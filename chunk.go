@@ -0,0 +1,272 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Chunk is a contiguous piece of a document.
+type Chunk struct {
+	// Text is the text of the chunk.
+	Text string
+	// Start is the byte offset of the chunk within the document.
+	Start int
+}
+
+// ChunkDocument splits a document into chunks of at most maxBytes, preferring to split between paragraphs
+// (at blank lines) and before markdown headings, then between lines, then between words, and only within a word as a last resort.
+// Each chunk starts with as many whole paragraphs from the end of the previous chunk as fit in overlapBytes,
+// so that answers near a split are fully contained in at least one chunk.
+// If maxBytes is not positive, or the document fits, it is returned as a single chunk.
+func ChunkDocument(documentText string, maxBytes int, overlapBytes int) []Chunk {
+	if maxBytes <= 0 || len(documentText) <= maxBytes {
+		return []Chunk{{Text: documentText, Start: 0}}
+	}
+	blocks := chunkBlocks(documentText, maxBytes)
+	chunks := make([]Chunk, 0)
+	first := 0
+	for {
+		last := first
+		for last+1 < len(blocks) && blocks[last+1].End-blocks[first].Start <= maxBytes {
+			last++
+		}
+		start, end := blocks[first].Start, blocks[last].End
+		chunks = append(chunks, Chunk{Text: documentText[start:end], Start: start})
+		if last == len(blocks)-1 {
+			return chunks
+		}
+		// Step back over the trailing blocks that fit in the overlap,
+		// but always move forwards and leave room for at least one new block.
+		next := last + 1
+		for next-1 > first && end-blocks[next-1].Start <= overlapBytes && blocks[last+1].End-blocks[next-1].Start <= maxBytes {
+			next--
+		}
+		first = next
+	}
+}
+
+// chunkBlocks splits a document into contiguous blocks that are each at most maxBytes,
+// which [ChunkDocument] then packs into chunks.
+func chunkBlocks(documentText string, maxBytes int) []Range {
+	blocks := make([]Range, 0)
+	for _, paragraph := range splitBefore(documentText, paragraphBoundaries(documentText)) {
+		if paragraph.Len() <= maxBytes {
+			blocks = append(blocks, paragraph)
+			continue
+		}
+		for _, line := range splitBefore(documentText[paragraph.Start:paragraph.End], lineBoundaries(documentText[paragraph.Start:paragraph.End])) {
+			line = Range{line.Start + paragraph.Start, line.End + paragraph.Start}
+			for line.Len() > maxBytes {
+				cut := line.Start + maxBytes
+				if space := strings.LastIndexByte(documentText[line.Start:cut], ' '); space > 0 {
+					cut = line.Start + space + 1
+				}
+				for cut > line.Start+1 && !utf8.RuneStart(documentText[cut]) {
+					cut--
+				}
+				blocks = append(blocks, Range{line.Start, cut})
+				line.Start = cut
+			}
+			blocks = append(blocks, line)
+		}
+	}
+	return blocks
+}
+
+// paragraphBoundaries finds the offsets that start a paragraph: the first non-blank line after a blank line, and every heading.
+func paragraphBoundaries(text string) []int {
+	boundaries := make([]int, 0)
+	prevBlank := false
+	for _, line := range splitBefore(text, lineBoundaries(text)) {
+		lineText := text[line.Start:line.End]
+		blank := strings.TrimSpace(lineText) == ""
+		heading := strings.HasPrefix(strings.TrimLeft(lineText, " "), "#")
+		if line.Start > 0 && !blank && (prevBlank || heading) {
+			boundaries = append(boundaries, line.Start)
+		}
+		prevBlank = blank
+	}
+	return boundaries
+}
+
+// lineBoundaries finds the offsets that start each line, other than the first.
+func lineBoundaries(text string) []int {
+	boundaries := make([]int, 0)
+	for i := 0; i < len(text)-1; i++ {
+		if text[i] == '\n' {
+			boundaries = append(boundaries, i+1)
+		}
+	}
+	return boundaries
+}
+
+// splitBefore splits text into contiguous ranges, starting a new range at each of the sorted boundaries.
+func splitBefore(text string, boundaries []int) []Range {
+	ranges := make([]Range, 0, len(boundaries)+1)
+	start := 0
+	for _, b := range boundaries {
+		ranges = append(ranges, Range{start, b})
+		start = b
+	}
+	return append(ranges, Range{start, len(text)})
+}
+
+// ExtractAnswersChunked is the same as [ExtractAnswersContext], but for documents too long to send in one request.
+// The document is split with [ChunkDocument], answers are extracted from each chunk,
+// and then the answers are merged in document order.
+//
+// Ranges in the [EntityAttributes] are shifted to be relative to the whole document.
+// Entities with the same type and content that were found in several chunks (for example in the overlap) are merged into one,
+// keeping the attributes of the first and adding the evidence of the others.
+// As a chunk may not contain the answer to a question, [Question.MinAnswers] is only checked once the answers are merged.
+// As different chunks may find different answers, only the first [Question.MaxAnswers] answers in document order are kept.
+// The returned usage is the sum of the usage of every chunk.
+func ExtractAnswersChunked(ctx context.Context, client Client, qa Protocol, questions map[string]Question, documentText string, maxChunkBytes int, overlapBytes int, opts ...ExtractOption) (map[string][]Entity, LLMUsage, error) {
+	if err := validateQuestions(questions); err != nil {
+		return nil, LLMUsage{}, err
+	}
+	chunks := ChunkDocument(documentText, maxChunkBytes, overlapBytes)
+	chunkQuestions := make(map[string]Question, len(questions))
+	for key, q := range questions {
+		q.MinAnswers = 0
+		chunkQuestions[key] = q
+	}
+	calls := make([]extractCall, len(chunks))
+	for i, chunk := range chunks {
+		calls[i] = extractCall{questions: chunkQuestions, text: chunk.Text}
+	}
+	results, usage, failed, err := extractAll(ctx, client, qa, calls, opts)
+	if err != nil {
+		chunk := chunks[failed]
+		return nil, usage, fmt.Errorf("chunk %d (bytes %d-%d) failed: %w", failed, chunk.Start, chunk.Start+len(chunk.Text), err)
+	}
+
+	for i, chunk := range chunks {
+		mapAnswerRanges(results[i], func(r Range) Range {
+			return Range{r.Start + chunk.Start, r.End + chunk.Start}
+		})
+	}
+	answers, err := mergeChunkAnswers(questions, results)
+	if err != nil {
+		return nil, usage, err
+	}
+	trimToMaxAnswers(questions, answers)
+	if err := ValidateCardinality(questions, answers); err != nil {
+		return nil, usage, fmt.Errorf("merged answers were invalid: %w", err)
+	}
+	return answers, usage, nil
+}
+
+// extractCall is a single call made by [ExtractAnswersChunked] or [ExtractAnswersRetrieved].
+type extractCall struct {
+	questions map[string]Question
+	text      string
+}
+
+// extractAll makes the calls with [ExtractAnswersContext], as many at once as [WithChunkParallelism] allows,
+// and cancels the rest as soon as one fails. If a call fails, its index and error are returned.
+func extractAll(ctx context.Context, client Client, qa Protocol, calls []extractCall, opts []ExtractOption) ([]map[string][]Entity, LLMUsage, int, error) {
+	cfg := newExtractConfig(opts)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]map[string][]Entity, len(calls))
+	usages := make([]LLMUsage, len(calls))
+	errs := make([]error, len(calls))
+	sem := make(chan struct{}, max(cfg.chunkParallelism, 1))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], usages[i], errs[i] = ExtractAnswersContext(ctx, client, qa, call.questions, call.text, opts...)
+			if errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	usage := SumUsage(usages...)
+	// Report the error that caused the others to be cancelled, rather than one of the cancellations.
+	failed := -1
+	for i, err := range errs {
+		if err != nil && (failed == -1 || errors.Is(errs[failed], context.Canceled) && !errors.Is(err, context.Canceled)) {
+			failed = i
+		}
+	}
+	if failed != -1 {
+		return nil, usage, failed, errs[failed]
+	}
+	return results, usage, -1, nil
+}
+
+// mapAnswerRanges converts the ranges of every answer, which are relative to the text that was sent to the LLM,
+// to be relative to the whole document.
+func mapAnswerRanges(answers map[string][]Entity, toDocument func(Range) Range) {
+	for _, entities := range answers {
+		for _, e := range entities {
+			attr := e.Attr()
+			for i, r := range attr.EvidenceRanges {
+				attr.EvidenceRanges[i] = toDocument(r)
+			}
+			if !attr.LocalisedRange.IsIndef() {
+				attr.LocalisedRange = toDocument(attr.LocalisedRange)
+			}
+		}
+	}
+}
+
+// mergeChunkAnswers concatenates the answers of each chunk, merging entities with the same identity.
+func mergeChunkAnswers(questions map[string]Question, results []map[string][]Entity) (map[string][]Entity, error) {
+	answers := make(map[string][]Entity, len(questions))
+	for key := range questions {
+		answers[key] = []Entity{}
+	}
+	seen := make(map[string]map[string]Entity)
+	for _, result := range results {
+		for _, qKey := range sortedKeys(result) {
+			if _, ok := seen[qKey]; !ok {
+				seen[qKey] = make(map[string]Entity)
+			}
+			for _, e := range result[qKey] {
+				id, err := entityIdentity(e)
+				if err != nil {
+					return nil, err
+				}
+				if existing, ok := seen[qKey][id]; ok {
+					mergeEntityAttributes(existing.Attr(), e.Attr())
+					continue
+				}
+				seen[qKey][id] = e
+				answers[qKey] = append(answers[qKey], e)
+			}
+		}
+	}
+	return answers, nil
+}
+
+// mergeEntityAttributes adds the evidence of a duplicate entity to the attributes of the entity that is kept.
+func mergeEntityAttributes(into *EntityAttributes, from *EntityAttributes) {
+	for _, r := range from.EvidenceRanges {
+		if !slices.Contains(into.EvidenceRanges, r) {
+			into.EvidenceRanges = append(into.EvidenceRanges, r)
+		}
+	}
+	for _, q := range from.UnresolvedEvidence {
+		if !slices.Contains(into.UnresolvedEvidence, q) {
+			into.UnresolvedEvidence = append(into.UnresolvedEvidence, q)
+		}
+	}
+	if into.LocalisedRange.IsIndef() {
+		into.LocalisedRange = from.LocalisedRange
+	}
+	if into.Confidence == nil {
+		into.Confidence = from.Confidence
+	}
+}
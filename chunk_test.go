@@ -0,0 +1,192 @@
+package docqa
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkDocument(t *testing.T) {
+	cases := []struct {
+		name     string
+		doc      string
+		maxBytes int
+		overlap  int
+		chunks   []Chunk
+	}{
+		{
+			name:     "fits in one chunk",
+			doc:      "short",
+			maxBytes: 10,
+			chunks:   []Chunk{{"short", 0}},
+		},
+		{
+			name:     "no limit",
+			doc:      "anything",
+			maxBytes: 0,
+			chunks:   []Chunk{{"anything", 0}},
+		},
+		{
+			name:     "paragraphs are packed",
+			doc:      "aaa\n\nbbb\n\nccc\n\nddd",
+			maxBytes: 10,
+			chunks:   []Chunk{{"aaa\n\nbbb\n\n", 0}, {"ccc\n\nddd", 10}},
+		},
+		{
+			name:     "overlap repeats trailing paragraphs",
+			doc:      "aaa\n\nbbb\n\nccc\n\nddd",
+			maxBytes: 10,
+			overlap:  5,
+			chunks:   []Chunk{{"aaa\n\nbbb\n\n", 0}, {"bbb\n\nccc\n\n", 5}, {"ccc\n\nddd", 10}},
+		},
+		{
+			name:     "overlap always leaves room for a new paragraph",
+			doc:      "aa\n\nbb\n\ncc\n\ndd\n\nee",
+			maxBytes: 8,
+			overlap:  8,
+			chunks:   []Chunk{{"aa\n\nbb\n\n", 0}, {"bb\n\ncc\n\n", 4}, {"cc\n\ndd\n\n", 8}, {"dd\n\nee", 12}},
+		},
+		{
+			name:     "split before headings",
+			doc:      "intro\n# H1\nbody one\n# H2\nbody two",
+			maxBytes: 16,
+			chunks:   []Chunk{{"intro\n", 0}, {"# H1\nbody one\n", 6}, {"# H2\nbody two", 20}},
+		},
+		{
+			name:     "long paragraph is split between lines",
+			doc:      "line one\nline two\nline three",
+			maxBytes: 12,
+			chunks:   []Chunk{{"line one\n", 0}, {"line two\n", 9}, {"line three", 18}},
+		},
+		{
+			name:     "long line is split between words",
+			doc:      "the quick brown fox jumps",
+			maxBytes: 10,
+			chunks:   []Chunk{{"the quick ", 0}, {"brown fox ", 10}, {"jumps", 20}},
+		},
+		{
+			name:     "long word is split",
+			doc:      "abcdefghijklmnop",
+			maxBytes: 5,
+			chunks:   []Chunk{{"abcde", 0}, {"fghij", 5}, {"klmno", 10}, {"p", 15}},
+		},
+		{
+			name:     "runes are not split",
+			doc:      "ééééé",
+			maxBytes: 3,
+			chunks:   []Chunk{{"é", 0}, {"é", 2}, {"é", 4}, {"é", 6}, {"é", 8}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chunks := ChunkDocument(tc.doc, tc.maxBytes, tc.overlap)
+			if !reflect.DeepEqual(chunks, tc.chunks) {
+				t.Errorf("expected chunks %q, got %q", tc.chunks, chunks)
+			}
+			covered := 0
+			for i, chunk := range chunks {
+				if tc.doc[chunk.Start:chunk.Start+len(chunk.Text)] != chunk.Text {
+					t.Errorf("chunk %d does not match the document at its start", i)
+				}
+				if tc.maxBytes > 0 && len(chunk.Text) > tc.maxBytes {
+					t.Errorf("chunk %d is %d bytes, more than the maximum of %d", i, len(chunk.Text), tc.maxBytes)
+				}
+				if chunk.Start > covered {
+					t.Errorf("chunk %d leaves a gap before byte %d", i, chunk.Start)
+				}
+				covered = chunk.Start + len(chunk.Text)
+			}
+			if covered != len(tc.doc) {
+				t.Errorf("chunks only cover %d of %d bytes", covered, len(tc.doc))
+			}
+		})
+	}
+}
+
+func TestExtractAnswersChunked(t *testing.T) {
+	// Each paragraph is its own chunk: "Alice signed.\n\n" at 0, "Bob signed.\n\n" at 15, and "Alice again." at 28.
+	doc := "Alice signed.\n\nBob signed.\n\nAlice again."
+	responses := map[string]string{
+		"Alice signed.\n\n": `{
+			"signers": [{"answer_type": "text", "text": "Alice", "evidence_quotes": ["Alice signed"]}],
+			"first": [{"answer_type": "text", "text": "Alice", "evidence_quotes": ["Alice"]}]
+		}`,
+		"Bob signed.\n\n": `{
+			"signers": [{"answer_type": "text", "text": "Bob", "evidence_quotes": ["Bob signed"]}],
+			"first": [{"answer_type": "text", "text": "Bob", "evidence_quotes": ["Bob"]}]
+		}`,
+		"Alice again.": `{
+			"signers": [{"answer_type": "text", "text": "Alice", "evidence_quotes": ["Alice again"]}],
+			"first": []
+		}`,
+	}
+	client := &stubClient{respond: func(_ int, _ string, messages []Message) (string, LLMUsage, error) {
+		resp, ok := responses[messages[0].Content]
+		if !ok {
+			return "", LLMUsage{}, fmt.Errorf("unexpected chunk %q", messages[0].Content)
+		}
+		return resp, LLMUsage{InputTokens: 10, OutputTokens: 2}, nil
+	}}
+	questions := map[string]Question{
+		"signers": {Question: "Who signed?", AllowedTypeKeys: []string{"text"}},
+		"first":   {Question: "Who signed first?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1, MaxAnswers: 1},
+	}
+	qa := newTestTextProtocol(WithEvidenceQuotes())
+
+	answers, usage, err := ExtractAnswersChunked(context.Background(), client, qa, questions, doc, 16, 0, WithChunkParallelism(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.calls) != 3 {
+		t.Fatalf("expected a call per chunk, got %d", len(client.calls))
+	}
+	if usage != (LLMUsage{InputTokens: 30, OutputTokens: 6}) {
+		t.Errorf("expected the usage of every chunk to be summed, got %+v", usage)
+	}
+
+	// Ranges are shifted to the document, and the Alice found in two chunks is merged.
+	expectedSigners := []struct {
+		text   string
+		ranges []Range
+	}{
+		{"Alice", []Range{{0, 12}, {28, 39}}},
+		{"Bob", []Range{{15, 25}}},
+	}
+	if len(answers["signers"]) != len(expectedSigners) {
+		t.Fatalf("expected %d signers, got %d", len(expectedSigners), len(answers["signers"]))
+	}
+	for i, want := range expectedSigners {
+		got := answers["signers"][i].(*testTextEntity)
+		if got.Text != want.text || !reflect.DeepEqual(got.EvidenceRanges, want.ranges) {
+			t.Errorf("signer %d: expected %s at %v, got %s at %v", i, want.text, want.ranges, got.Text, got.EvidenceRanges)
+		}
+		for _, r := range got.EvidenceRanges {
+			if !strings.HasPrefix(doc[r.Start:r.End], want.text) {
+				t.Errorf("signer %d: evidence range %v covers %q", i, r, doc[r.Start:r.End])
+			}
+		}
+	}
+
+	// Two chunks found a different answer to an "exactly 1" question, so the first in document order is kept.
+	if len(answers["first"]) != 1 || answers["first"][0].(*testTextEntity).Text != "Alice" {
+		t.Errorf("expected only the first answer in document order to be kept, got %v", answers["first"])
+	}
+
+	t.Run("too few answers once merged", func(t *testing.T) {
+		questions := map[string]Question{
+			"last": {Question: "Who signed last?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1},
+		}
+		client := &stubClient{respond: func(int, string, []Message) (string, LLMUsage, error) {
+			return `{"last": []}`, LLMUsage{}, nil
+		}}
+		_, _, err := ExtractAnswersChunked(context.Background(), client, qa, questions, doc, 16, 0)
+		if err == nil || !strings.Contains(err.Error(), `question "last" has 0 answers, but needs at least 1`) {
+			t.Errorf("expected the merged answers to fail the minimum, got %v", err)
+		}
+		if len(client.calls) != 3 {
+			t.Errorf("expected each chunk to be allowed no answers, got %d calls", len(client.calls))
+		}
+	})
+}
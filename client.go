@@ -1,11 +1,10 @@
 package docqa
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
+	"strings"
+	"time"
 )
 
 // Client is a wrapper around an LLM with schema capabilities.
@@ -14,74 +13,185 @@ type Client interface {
 	GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error)
 }
 
+// ContextClient is a [Client] that can be cancelled, or given a deadline, with a [context.Context].
+type ContextClient interface {
+	Client
+	// GetLLMResponseContext is the same as [Client.GetLLMResponse], but gives up as soon as ctx is done.
+	GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error)
+}
+
+// StreamingClient is a [ContextClient] that can deliver its response incrementally, as it is generated.
+type StreamingClient interface {
+	ContextClient
+	// StreamLLMResponse is the same as [ContextClient.GetLLMResponseContext], but calls onChunk with each
+	// piece of the response text as it arrives. If onChunk returns an error, the stream is abandoned and that error is returned.
+	StreamLLMResponse(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any, onChunk func(string) error) (string, LLMUsage, error)
+}
+
+// LogprobClient is a [ContextClient] that can also report how likely each generated token was.
+type LogprobClient interface {
+	ContextClient
+	// GetLLMResponseLogprobs is the same as [ContextClient.GetLLMResponseContext], but also returns the log probability
+	// of each token of the response. Concatenating the bytes of the tokens gives the response text.
+	GetLLMResponseLogprobs(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, []TokenLogprob, LLMUsage, error)
+}
+
+// ConversationClient is a [ContextClient] that can be given a whole conversation, rather than a single user prompt.
+type ConversationClient interface {
+	ContextClient
+	// GetLLMConversationResponse is the same as [ContextClient.GetLLMResponseContext], but the model continues
+	// the given conversation. Messages alternate between [UserRole] and [AssistantRole], starting and ending with [UserRole].
+	GetLLMConversationResponse(ctx context.Context, systemPrompt string, messages []Message, schema map[string]any) (string, LLMUsage, error)
+}
+
+// The roles a [Message] can have.
+const (
+	UserRole      = "user"
+	AssistantRole = "assistant"
+)
+
+// Message is a single turn of a conversation with an LLM.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// TokenLogprob is a single generated token, and the natural log of the probability the model gave it.
+type TokenLogprob struct {
+	Token   string
+	Logprob float64
+	// Bytes is the raw UTF-8 of the token, if known. A token may be only part of a multi-byte character,
+	// in which case Token is not the exact text, and Bytes must be used to find where the token sits in the response.
+	Bytes []byte
+}
+
+// byteLen is the number of bytes of the response covered by the token.
+func (t TokenLogprob) byteLen() int {
+	if t.Bytes != nil {
+		return len(t.Bytes)
+	}
+	return len(t.Token)
+}
+
+// LLMUsage describes how many tokens were used by one or more LLM calls.
 type LLMUsage struct {
-	InputTokens  int
+	// InputTokens is the total number of prompt tokens, including any cached tokens.
+	InputTokens int
+	// OutputTokens is the total number of generated tokens, including any reasoning tokens.
 	OutputTokens int
+	// CachedInputTokens is the number of the input tokens that were served from the provider's prompt cache.
+	CachedInputTokens int
+	// ReasoningTokens is the number of the output tokens that were spent on hidden reasoning.
+	ReasoningTokens int
+	// Provider names the provider that produced the response, if known (see [NewFallbackClient]).
+	Provider string
 }
 
-type openAIClient struct {
-	key   string
-	model string
+// Add returns the combined usage of u and other.
+// The provider of other is kept if it is set, as it is assumed to describe the later call.
+func (u LLMUsage) Add(other LLMUsage) LLMUsage {
+	provider := u.Provider
+	if other.Provider != "" {
+		provider = other.Provider
+	}
+	return LLMUsage{
+		InputTokens:       u.InputTokens + other.InputTokens,
+		OutputTokens:      u.OutputTokens + other.OutputTokens,
+		CachedInputTokens: u.CachedInputTokens + other.CachedInputTokens,
+		ReasoningTokens:   u.ReasoningTokens + other.ReasoningTokens,
+		Provider:          provider,
+	}
 }
 
-// NewOpenAIClient creates a new client that communicates with the OpenAI API.
-func NewOpenAIClient(key, model string) Client {
-	return &openAIClient{
-		key:   key,
-		model: model,
+// SumUsage returns the combined usage of all the given usages.
+func SumUsage(usages ...LLMUsage) LLMUsage {
+	var total LLMUsage
+	for _, u := range usages {
+		total = total.Add(u)
 	}
+	return total
 }
 
-// GetLLMResponse implements [Client].
-func (c *openAIClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
-	bodyMap := map[string]any{
-		"model":           c.model,
-		"temperature":     0.1,
-		"response_format": wrapOpenAISchema(schema),
-		"messages": []map[string]any{
-			{
-				"role":    "system",
-				"content": systemPrompt,
-			},
-			{
-				"role":    "user",
-				"content": userPrompt,
-			},
-		},
-	}
-	body, err := json.Marshal(bodyMap)
-	if err != nil {
+// GetLLMResponseWithContext prompts any [Client] with a context.
+// If the client is a [ContextClient] the context is passed through to it.
+// Otherwise, the call is run in the background and abandoned (but not stopped) if ctx is done first.
+func GetLLMResponseWithContext(ctx context.Context, client Client, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	if err := ctx.Err(); err != nil {
 		return "", LLMUsage{}, err
 	}
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(body))
-	if err != nil {
-		return "", LLMUsage{}, err
+	if cc, ok := client.(ContextClient); ok {
+		return cc.GetLLMResponseContext(ctx, systemPrompt, userPrompt, schema)
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.key))
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", LLMUsage{}, err
+	type result struct {
+		resp  string
+		usage LLMUsage
+		err   error
 	}
-	defer resp.Body.Close()
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", LLMUsage{}, err
+	done := make(chan result, 1)
+	go func() {
+		resp, usage, err := client.GetLLMResponse(systemPrompt, userPrompt, schema)
+		done <- result{resp, usage, err}
+	}()
+	select {
+	case r := <-done:
+		return r.resp, r.usage, r.err
+	case <-ctx.Done():
+		return "", LLMUsage{}, ctx.Err()
 	}
-	respTyped := struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Usage struct {
-			InputTokens  int `json:"prompt_tokens"`
-			OutputTokens int `json:"completion_tokens"`
+}
+
+// GetLLMConversationResponseWithContext prompts any [Client] with a conversation.
+// If the client is a [ConversationClient] the messages are passed through to it.
+// Otherwise, the conversation is written out as a single user prompt, and sent with [GetLLMResponseWithContext].
+func GetLLMConversationResponseWithContext(ctx context.Context, client Client, systemPrompt string, messages []Message, schema map[string]any) (string, LLMUsage, error) {
+	if cc, ok := client.(ConversationClient); ok {
+		if err := ctx.Err(); err != nil {
+			return "", LLMUsage{}, err
 		}
-	}{}
-	err = json.Unmarshal(respBody, &respTyped)
-	if err != nil || len(respTyped.Choices) == 0 || respTyped.Choices[0].Message.Content == "" {
-		return "", LLMUsage(respTyped.Usage), fmt.Errorf("failed to parse response: %s", string(respBody))
+		return cc.GetLLMConversationResponse(ctx, systemPrompt, messages, schema)
+	}
+	return GetLLMResponseWithContext(ctx, client, systemPrompt, flattenConversation(messages), schema)
+}
+
+// userMessages is the conversation made of a single user prompt.
+func userMessages(userPrompt string) []Message {
+	return []Message{{Role: UserRole, Content: userPrompt}}
+}
+
+// flattenConversation writes a conversation out as a single prompt, for clients that only accept one.
+func flattenConversation(messages []Message) string {
+	if len(messages) == 1 {
+		return messages[0].Content
+	}
+	var sb strings.Builder
+	sb.WriteString("The following is the conversation so far. Respond to the final user message.\n")
+	for _, m := range messages {
+		fmt.Fprintf(&sb, "\n### %s\n%s\n", m.Role, m.Content)
 	}
-	return respTyped.Choices[0].Message.Content, LLMUsage(respTyped.Usage), nil
+	return sb.String()
+}
+
+type timeoutClient struct {
+	client  Client
+	timeout time.Duration
+}
+
+// NewTimeoutClient wraps a [Client] so that every call is given at most the timeout to complete.
+func NewTimeoutClient(client Client, timeout time.Duration) ContextClient {
+	return &timeoutClient{
+		client:  client,
+		timeout: timeout,
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *timeoutClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *timeoutClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return GetLLMResponseWithContext(ctx, c.client, systemPrompt, userPrompt, schema)
 }
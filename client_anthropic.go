@@ -0,0 +1,123 @@
+package docqa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	anthropicToolName  = "submit_answers"
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 8192
+)
+
+type anthropicClient struct {
+	key   string
+	model string
+	cfg   *httpConfig
+}
+
+// NewAnthropicClient creates a new client that communicates with the Anthropic Messages API.
+// The schema is enforced by forcing the model to call a tool whose input schema is the requested schema.
+// The returned client also implements [ConversationClient].
+func NewAnthropicClient(key, model string, opts ...ClientOption) ContextClient {
+	return &anthropicClient{
+		key:   key,
+		model: model,
+		cfg:   newHTTPConfig("https://api.anthropic.com/v1", HeaderAuth("x-api-key"), opts),
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *anthropicClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *anthropicClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMConversationResponse(ctx, systemPrompt, userMessages(userPrompt), schema)
+}
+
+// GetLLMConversationResponse implements [ConversationClient].
+// Earlier assistant turns are sent as plain text, as they may not have come from this client's tool call.
+func (c *anthropicClient) GetLLMConversationResponse(ctx context.Context, systemPrompt string, messages []Message, schema map[string]any) (string, LLMUsage, error) {
+	anthropicMessages := make([]map[string]any, 0, len(messages))
+	for _, m := range messages {
+		anthropicMessages = append(anthropicMessages, map[string]any{
+			"role":    m.Role,
+			"content": m.Content,
+		})
+	}
+	bodyMap := map[string]any{
+		"model":       c.model,
+		"max_tokens":  anthropicMaxTokens,
+		"temperature": c.cfg.temperature,
+		"system":      systemPrompt,
+		"messages":    anthropicMessages,
+		"tools": []map[string]any{
+			{
+				"name":         anthropicToolName,
+				"description":  "Submit the answers to the questions, following the schema exactly",
+				"input_schema": schema,
+			},
+		},
+		"tool_choice": map[string]any{
+			"type": "tool",
+			"name": anthropicToolName,
+		},
+	}
+	req, err := c.cfg.newRequest(ctx, "/messages", c.key, bodyMap)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	req.Header.Add("anthropic-version", anthropicVersion)
+	respBody, err := c.cfg.do(req)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	respTyped := struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+			Text  string          `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		} `json:"usage"`
+	}{}
+	err = json.Unmarshal(respBody, &respTyped)
+	if err != nil {
+		return "", LLMUsage{}, newMalformedResponseError(string(respBody), LLMUsage{}, err)
+	}
+	// Anthropic reports cached tokens separately from input tokens, but LLMUsage counts them as part of the input.
+	// Cache writes are counted as plain input, as LLMUsage has no separate count for them (see PriceTable.Cost).
+	usage := LLMUsage{
+		InputTokens:       respTyped.Usage.InputTokens + respTyped.Usage.CacheReadInputTokens + respTyped.Usage.CacheCreationInputTokens,
+		OutputTokens:      respTyped.Usage.OutputTokens,
+		CachedInputTokens: respTyped.Usage.CacheReadInputTokens,
+	}
+	content, text := "", ""
+	for _, block := range respTyped.Content {
+		if block.Type == "tool_use" && block.Name == anthropicToolName && len(block.Input) > 0 {
+			content = string(block.Input)
+		}
+		text += block.Text
+	}
+	partial := PartialResponse{Content: content, Usage: usage}
+	switch respTyped.StopReason {
+	case "max_tokens":
+		return "", usage, &TruncatedError{PartialResponse: partial}
+	case "refusal":
+		return "", usage, &RefusalError{PartialResponse: partial, Refusal: text}
+	}
+	if content == "" {
+		return "", usage, newMalformedResponseError(string(respBody), usage, fmt.Errorf("response did not contain a %s tool call", anthropicToolName))
+	}
+	return content, usage, nil
+}
@@ -0,0 +1,122 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAnthropicClientRequest(t *testing.T) {
+	server, recorded := newRecordingServer(t, http.StatusOK, `{
+		"content": [{"type": "text", "text": "Submitting."}, {"type": "tool_use", "name": "submit_answers", "input": {"a": 1}}],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 4, "cache_creation_input_tokens": 2}
+	}`)
+	client := NewAnthropicClient("key", "claude-test", WithBaseURL(server.URL), WithTemperature(0.5))
+	schema := map[string]any{"type": "object", "properties": map[string]any{"a": map[string]any{"type": "integer"}}}
+	resp, usage, err := client.GetLLMResponseContext(context.Background(), "system", "user", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp != `{"a": 1}` {
+		t.Errorf("expected the tool input, got %q", resp)
+	}
+	expectedUsage := LLMUsage{InputTokens: 16, OutputTokens: 5, CachedInputTokens: 4}
+	if usage != expectedUsage {
+		t.Errorf("expected usage %+v, got %+v", expectedUsage, usage)
+	}
+	if recorded.Path != "/messages" {
+		t.Errorf("unexpected path %q", recorded.Path)
+	}
+	if got := recorded.Header.Get("x-api-key"); got != "key" {
+		t.Errorf("expected api key header, got %q", got)
+	}
+	if got := recorded.Header.Get("anthropic-version"); got != anthropicVersion {
+		t.Errorf("expected anthropic-version %s, got %q", anthropicVersion, got)
+	}
+	if got := recorded.Body["system"]; got != "system" {
+		t.Errorf("expected the system prompt at the top level, got %v", got)
+	}
+	if got := recorded.Body["temperature"]; got != 0.5 {
+		t.Errorf("expected temperature 0.5, got %v", got)
+	}
+	if got := jsonPath(t, recorded.Body, "messages", 0, "content"); got != "user" {
+		t.Errorf("expected the user prompt as the first message, got %v", got)
+	}
+	if got := jsonPath(t, recorded.Body, "tool_choice", "name"); got != anthropicToolName {
+		t.Errorf("expected the tool to be forced, got %v", got)
+	}
+	if got := jsonPath(t, recorded.Body, "tools", 0, "input_schema"); !reflect.DeepEqual(got, schema) {
+		t.Errorf("expected the schema as the tool input schema, got %v", got)
+	}
+}
+
+func TestAnthropicClientStopReasons(t *testing.T) {
+	usageJSON := `"usage": {"input_tokens": 2, "output_tokens": 1}`
+	cases := []struct {
+		name     string
+		response string
+		check    func(t *testing.T, err error)
+	}{
+		{
+			name:     "max tokens",
+			response: `{"content": [{"type": "tool_use", "name": "submit_answers", "input": {}}], "stop_reason": "max_tokens", ` + usageJSON + `}`,
+			check: func(t *testing.T, err error) {
+				var truncated *TruncatedError
+				if !errors.As(err, &truncated) {
+					t.Fatalf("expected a TruncatedError, got %v", err)
+				}
+			},
+		},
+		{
+			name:     "refusal",
+			response: `{"content": [{"type": "text", "text": "I cannot help."}], "stop_reason": "refusal", ` + usageJSON + `}`,
+			check: func(t *testing.T, err error) {
+				var refusal *RefusalError
+				if !errors.As(err, &refusal) {
+					t.Fatalf("expected a RefusalError, got %v", err)
+				}
+				if refusal.Refusal != "I cannot help." {
+					t.Errorf("expected the refusal text, got %q", refusal.Refusal)
+				}
+			},
+		},
+		{
+			name:     "no tool call",
+			response: `{"content": [{"type": "text", "text": "{}"}], "stop_reason": "end_turn", ` + usageJSON + `}`,
+			check: func(t *testing.T, err error) {
+				var malformed *MalformedResponseError
+				if !errors.As(err, &malformed) {
+					t.Fatalf("expected a MalformedResponseError, got %v", err)
+				}
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _ := newRecordingServer(t, http.StatusOK, tc.response)
+			client := NewAnthropicClient("key", "claude-test", WithBaseURL(server.URL))
+			_, usage, err := client.GetLLMResponseContext(context.Background(), "system", "user", map[string]any{"type": "object"})
+			if usage != (LLMUsage{InputTokens: 2, OutputTokens: 1}) {
+				t.Errorf("expected usage to be returned with the error, got %+v", usage)
+			}
+			tc.check(t, err)
+		})
+	}
+}
+
+func TestAnthropicClientStatusError(t *testing.T) {
+	server, _ := newRecordingServer(t, http.StatusTooManyRequests, `{"type": "error"}`)
+	client := NewAnthropicClient("key", "claude-test", WithBaseURL(server.URL))
+	_, _, err := client.GetLLMResponseContext(context.Background(), "system", "user", map[string]any{"type": "object"})
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected a 429 HTTPStatusError, got %v", err)
+	}
+	if !IsRetryable(err) {
+		t.Errorf("expected a 429 to be retryable")
+	}
+}
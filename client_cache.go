@@ -0,0 +1,114 @@
+package docqa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+type cacheClient struct {
+	client Client
+	dir    string
+	model  string
+}
+
+type cacheEntry struct {
+	Response string   `json:"response"`
+	Usage    LLMUsage `json:"usage"`
+}
+
+// NewCacheClient wraps a [Client] so that responses are stored on disk, in a content-addressed directory,
+// and served from there when the same request is made again.
+// Requests are keyed on a hash of the model, system prompt, user prompt and schema,
+// so the model should name whatever the wrapped client talks to.
+// Cache hits report a zero [LLMUsage], as they cost nothing. Failed calls are never cached.
+func NewCacheClient(client Client, dir string, model string) ContextClient {
+	return &cacheClient{
+		client: client,
+		dir:    dir,
+		model:  model,
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *cacheClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *cacheClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	key, err := c.key(systemPrompt, userPrompt, schema)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	path := filepath.Join(c.dir, key[:2], key+".json")
+	if entry, ok := readCacheEntry(path); ok {
+		return entry.Response, LLMUsage{}, nil
+	}
+	resp, usage, err := GetLLMResponseWithContext(ctx, c.client, systemPrompt, userPrompt, schema)
+	if err != nil {
+		return "", usage, err
+	}
+	err = writeCacheEntry(path, cacheEntry{Response: resp, Usage: usage})
+	if err != nil {
+		return "", usage, err
+	}
+	return resp, usage, nil
+}
+
+// key hashes everything that affects the response.
+// Json encoding sorts map keys, so the hash is stable for equal schemas.
+func (c *cacheClient) key(systemPrompt string, userPrompt string, schema map[string]any) (string, error) {
+	bs, err := json.Marshal(map[string]any{
+		"model":         c.model,
+		"system_prompt": systemPrompt,
+		"user_prompt":   userPrompt,
+		"schema":        schema,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readCacheEntry loads a cache entry, treating any unreadable entry as a miss.
+func readCacheEntry(path string) (cacheEntry, bool) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(bs, &entry); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// writeCacheEntry writes the entry to a temporary file then renames it into place,
+// so concurrent readers never see a partially written entry.
+func writeCacheEntry(path string, entry cacheEntry) error {
+	bs, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
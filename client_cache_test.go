@@ -0,0 +1,95 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheClient(t *testing.T) {
+	dir := t.TempDir()
+	usage := LLMUsage{InputTokens: 10, OutputTokens: 2}
+	schema := map[string]any{"type": "object"}
+	calls := 0
+	failNext := false
+	inner := &stubClient{respond: func(int, string, []Message) (string, LLMUsage, error) {
+		if failNext {
+			failNext = false
+			return "", usage, errors.New("offline")
+		}
+		calls++
+		return "response", usage, nil
+	}}
+	client := NewCacheClient(inner, dir, "model-a")
+
+	steps := []struct {
+		name   string
+		client ContextClient
+		user   string
+		schema map[string]any
+		fail   bool
+		// hit is whether the response should come from the cache, without calling the inner client.
+		hit bool
+	}{
+		{name: "miss", client: client, user: "doc", schema: schema},
+		{name: "hit", client: client, user: "doc", schema: schema, hit: true},
+		{name: "equal schema hits", client: client, user: "doc", schema: map[string]any{"type": "object"}, hit: true},
+		{name: "different prompt misses", client: client, user: "other doc", schema: schema},
+		{name: "different schema misses", client: client, user: "doc", schema: map[string]any{"type": "array"}},
+		{name: "different model misses", client: NewCacheClient(inner, dir, "model-b"), user: "doc", schema: schema},
+		{name: "new client shares the directory", client: NewCacheClient(inner, dir, "model-a"), user: "doc", schema: schema, hit: true},
+		{name: "failure is not cached", client: client, user: "failing doc", schema: schema, fail: true},
+		{name: "failed request is retried", client: client, user: "failing doc", schema: schema},
+	}
+	for _, step := range steps {
+		before := calls
+		failNext = step.fail
+		resp, gotUsage, err := step.client.GetLLMResponseContext(context.Background(), "system", step.user, step.schema)
+		if step.fail {
+			if err == nil {
+				t.Errorf("%s: expected the error to be returned", step.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if resp != "response" {
+			t.Errorf("%s: unexpected response %q", step.name, resp)
+		}
+		if hit := calls == before; hit != step.hit {
+			t.Errorf("%s: expected hit to be %v", step.name, step.hit)
+		}
+		wantUsage := usage
+		if step.hit {
+			wantUsage = LLMUsage{}
+		}
+		if gotUsage != wantUsage {
+			t.Errorf("%s: expected usage %+v, got %+v", step.name, wantUsage, gotUsage)
+		}
+	}
+
+	t.Run("corrupt entry is a miss", func(t *testing.T) {
+		dir := t.TempDir()
+		client := NewCacheClient(inner, dir, "model-a")
+		if _, _, err := client.GetLLMResponse("system", "doc", schema); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("expected one cache entry, got %v (%v)", entries, err)
+		}
+		if err := os.WriteFile(entries[0], []byte("{"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		before := calls
+		if _, _, err := client.GetLLMResponse("system", "doc", schema); err != nil {
+			t.Fatal(err)
+		}
+		if calls != before+1 {
+			t.Errorf("expected the inner client to be called again")
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package docqa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+// PromptNormaliser rewrites a prompt before recorded and incoming requests are compared,
+// so that volatile parts of the prompt (dates, ids, ...) do not prevent a match.
+type PromptNormaliser func(string) string
+
+// IgnorePattern builds a [PromptNormaliser] that blanks out every match of the regular expression.
+func IgnorePattern(re *regexp.Regexp) PromptNormaliser {
+	return func(s string) string {
+		return re.ReplaceAllString(s, "<ignored>")
+	}
+}
+
+// CassetteOption configures a recording or replaying cassette [Client].
+type CassetteOption func(*cassetteMatcher)
+
+// WithPromptNormaliser adds a [PromptNormaliser] that is applied to both system and user prompts when matching.
+func WithPromptNormaliser(normaliser PromptNormaliser) CassetteOption {
+	return func(m *cassetteMatcher) {
+		m.normalisers = append(m.normalisers, normaliser)
+	}
+}
+
+// WithoutSchemaMatching makes requests match regardless of their schema.
+func WithoutSchemaMatching() CassetteOption {
+	return func(m *cassetteMatcher) {
+		m.ignoreSchema = true
+	}
+}
+
+// CassetteMissError is returned when replaying and no recorded interaction matches the request.
+type CassetteMissError struct {
+	Path         string
+	SystemPrompt string
+	UserPrompt   string
+}
+
+// Error implements error.
+func (e *CassetteMissError) Error() string {
+	return fmt.Sprintf(
+		"no interaction in cassette %s matches the request (system prompt %q, user prompt %q)",
+		e.Path, truncateForError(e.SystemPrompt), truncateForError(e.UserPrompt),
+	)
+}
+
+type cassetteInteraction struct {
+	SystemPrompt string         `json:"system_prompt"`
+	UserPrompt   string         `json:"user_prompt"`
+	Schema       map[string]any `json:"schema"`
+	Response     string         `json:"response"`
+	Usage        LLMUsage       `json:"usage"`
+}
+
+type cassetteFile struct {
+	Interactions []cassetteInteraction `json:"interactions"`
+}
+
+type cassetteMatcher struct {
+	normalisers  []PromptNormaliser
+	ignoreSchema bool
+}
+
+func newCassetteMatcher(opts []CassetteOption) cassetteMatcher {
+	m := cassetteMatcher{}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	return m
+}
+
+func (m cassetteMatcher) normalise(s string) string {
+	for _, n := range m.normalisers {
+		s = n(s)
+	}
+	return s
+}
+
+func (m cassetteMatcher) matches(recorded cassetteInteraction, systemPrompt, userPrompt string, schema map[string]any) bool {
+	if m.normalise(recorded.SystemPrompt) != m.normalise(systemPrompt) {
+		return false
+	}
+	if m.normalise(recorded.UserPrompt) != m.normalise(userPrompt) {
+		return false
+	}
+	if m.ignoreSchema {
+		return true
+	}
+	// Compare through json, as the recorded schema has been through a json round trip.
+	bs, err := json.Marshal(schema)
+	if err != nil {
+		return false
+	}
+	var roundTripped map[string]any
+	if err := json.Unmarshal(bs, &roundTripped); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(recorded.Schema, roundTripped)
+}
+
+type recordingClient struct {
+	client Client
+	path   string
+	lock   sync.Mutex
+	file   cassetteFile
+}
+
+// NewRecordingClient wraps a real [Client] and records every successful request/response pair
+// to a new cassette file at path, which can later be served with [NewReplayClient].
+// The file is rewritten after every call, so it is always complete.
+func NewRecordingClient(client Client, path string) ContextClient {
+	return &recordingClient{
+		client: client,
+		path:   path,
+		file:   cassetteFile{Interactions: make([]cassetteInteraction, 0)},
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *recordingClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *recordingClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	resp, usage, err := GetLLMResponseWithContext(ctx, c.client, systemPrompt, userPrompt, schema)
+	if err != nil {
+		return "", usage, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.file.Interactions = append(c.file.Interactions, cassetteInteraction{
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Schema:       schema,
+		Response:     resp,
+		Usage:        usage,
+	})
+	bs, err := json.MarshalIndent(c.file, "", "  ")
+	if err != nil {
+		return "", usage, err
+	}
+	if err := os.WriteFile(c.path, bs, 0o644); err != nil {
+		return "", usage, err
+	}
+	return resp, usage, nil
+}
+
+type replayClient struct {
+	path    string
+	matcher cassetteMatcher
+	lock    sync.Mutex
+	file    cassetteFile
+	used    []bool
+}
+
+// NewReplayClient creates a [Client] that never touches the network, and instead serves the responses
+// from a cassette written by [NewRecordingClient]. Any request that does not match a recorded interaction
+// fails with a [*CassetteMissError].
+// Identical requests are served in the order they were recorded, then the last match is repeated.
+func NewReplayClient(path string, opts ...CassetteOption) (ContextClient, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(bs, &file); err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+	}
+	return &replayClient{
+		path:    path,
+		matcher: newCassetteMatcher(opts),
+		file:    file,
+		used:    make([]bool, len(file.Interactions)),
+	}, nil
+}
+
+// GetLLMResponse implements [Client].
+func (c *replayClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *replayClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	if err := ctx.Err(); err != nil {
+		return "", LLMUsage{}, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	lastMatch := -1
+	for i, interaction := range c.file.Interactions {
+		if !c.matcher.matches(interaction, systemPrompt, userPrompt, schema) {
+			continue
+		}
+		lastMatch = i
+		if !c.used[i] {
+			c.used[i] = true
+			return interaction.Response, interaction.Usage, nil
+		}
+	}
+	if lastMatch >= 0 {
+		interaction := c.file.Interactions[lastMatch]
+		return interaction.Response, interaction.Usage, nil
+	}
+	return "", LLMUsage{}, &CassetteMissError{
+		Path:         c.path,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+	}
+}
@@ -0,0 +1,121 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	schema := map[string]any{"type": "object", "properties": map[string]any{"n": map[string]any{"type": "integer", "maximum": 3}}}
+	usage := LLMUsage{InputTokens: 10, OutputTokens: 1}
+	inner := stubResponses(usage, `{"n": 1}`, `{"n": 2}`, `{"n": 3}`)
+	failing := &stubClient{respond: func(int, string, []Message) (string, LLMUsage, error) {
+		return "", LLMUsage{}, errors.New("offline")
+	}}
+
+	recorder := NewRecordingClient(inner, path)
+	requests := []struct{ system, user string }{
+		{"Count. Today is 2024-01-01.", "one"},
+		{"Count. Today is 2024-01-01.", "one"},
+		{"Count. Today is 2024-01-01.", "two"},
+	}
+	for _, r := range requests {
+		if _, _, err := recorder.GetLLMResponseContext(context.Background(), r.system, r.user, schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Failed calls are not recorded.
+	if _, _, err := NewRecordingClient(failing, filepath.Join(t.TempDir(), "failed.json")).GetLLMResponse("s", "u", schema); err == nil {
+		t.Fatal("expected the recording client to return the error")
+	}
+
+	replay, err := NewReplayClient(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		system string
+		user   string
+		resp   string
+	}{
+		{"first of identical requests", requests[0].system, "one", `{"n": 1}`},
+		{"second of identical requests", requests[0].system, "one", `{"n": 2}`},
+		{"last match repeats", requests[0].system, "one", `{"n": 2}`},
+		{"different prompt", requests[0].system, "two", `{"n": 3}`},
+	}
+	for _, tc := range cases {
+		resp, gotUsage, err := replay.GetLLMResponseContext(context.Background(), tc.system, tc.user, schema)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if resp != tc.resp || gotUsage != usage {
+			t.Errorf("%s: expected %s with usage %+v, got %s with %+v", tc.name, tc.resp, usage, resp, gotUsage)
+		}
+	}
+	if len(inner.calls) != 3 {
+		t.Errorf("expected replaying not to call the real client, got %d calls", len(inner.calls))
+	}
+}
+
+func TestCassetteMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	schema := map[string]any{"type": "object", "required": []string{"a"}}
+	recorder := NewRecordingClient(stubResponses(LLMUsage{}, "recorded"), path)
+	if _, _, err := recorder.GetLLMResponse("Today is 2024-01-01.", "doc-123", schema); err != nil {
+		t.Fatal(err)
+	}
+	dates := IgnorePattern(regexp.MustCompile(`\d{4}-\d{2}-\d{2}`))
+	ids := IgnorePattern(regexp.MustCompile(`doc-\d+`))
+	otherSchema := map[string]any{"type": "object"}
+
+	cases := []struct {
+		name   string
+		opts   []CassetteOption
+		system string
+		user   string
+		schema map[string]any
+		hit    bool
+	}{
+		{"exact", nil, "Today is 2024-01-01.", "doc-123", schema, true},
+		{"different date", nil, "Today is 2025-06-30.", "doc-123", schema, false},
+		{"different date ignored", []CassetteOption{WithPromptNormaliser(dates)}, "Today is 2025-06-30.", "doc-123", schema, true},
+		{"different id ignored", []CassetteOption{WithPromptNormaliser(ids)}, "Today is 2024-01-01.", "doc-456", schema, true},
+		{"normalisers combine", []CassetteOption{WithPromptNormaliser(dates), WithPromptNormaliser(ids)}, "Today is 2025-06-30.", "doc-456", schema, true},
+		{"normaliser only ignores its pattern", []CassetteOption{WithPromptNormaliser(dates)}, "Yesterday was 2025-06-30.", "doc-123", schema, false},
+		{"different schema", nil, "Today is 2024-01-01.", "doc-123", otherSchema, false},
+		{"schema ignored", []CassetteOption{WithoutSchemaMatching()}, "Today is 2024-01-01.", "doc-123", otherSchema, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			replay, err := NewReplayClient(path, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, _, err := replay.GetLLMResponse(tc.system, tc.user, tc.schema)
+			if tc.hit {
+				if err != nil || resp != "recorded" {
+					t.Errorf("expected a hit, got %q, %v", resp, err)
+				}
+				return
+			}
+			var miss *CassetteMissError
+			if !errors.As(err, &miss) {
+				t.Fatalf("expected a CassetteMissError, got %v", err)
+			}
+			if miss.Path != path || miss.SystemPrompt != tc.system || miss.UserPrompt != tc.user {
+				t.Errorf("expected the miss to describe the request, got %+v", miss)
+			}
+		})
+	}
+
+	t.Run("missing cassette", func(t *testing.T) {
+		if _, err := NewReplayClient(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("expected an error for a cassette that does not exist")
+		}
+	})
+}
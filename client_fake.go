@@ -0,0 +1,180 @@
+package docqa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fakeMaxDepth = 8
+
+var fakeWords = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliet", "kilo", "lima", "mike", "november", "oscar", "papa",
+}
+
+type fakeClient struct {
+	lock sync.Mutex
+	rng  *rand.Rand
+}
+
+// NewFakeClient creates a [Client] that never calls an LLM, and instead responds with random json
+// that is valid according to the requested schema. This is useful to fuzz [Protocol]s and [Type]s.
+// The same seed produces the same sequence of responses for the same sequence of requests.
+//
+// The fake understands `type`, `properties`, `required`, `items`, `minItems`, `maxItems`,
+// `minimum`, `maximum`, `enum`, `const`, `anyOf`, `oneOf`, `format: date-time`, and local `$ref`s.
+func NewFakeClient(seed uint64) ContextClient {
+	return &fakeClient{
+		rng: rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *fakeClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *fakeClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	if err := ctx.Err(); err != nil {
+		return "", LLMUsage{}, err
+	}
+	c.lock.Lock()
+	value, err := c.generate(schema, schema, 0)
+	c.lock.Unlock()
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	return string(bs), LLMUsage{}, nil
+}
+
+func (c *fakeClient) generate(node, root map[string]any, depth int) (any, error) {
+	if ref, ok := node["$ref"].(string); ok {
+		resolved, err := resolveSchemaRef(root, ref)
+		if err != nil {
+			return nil, err
+		}
+		return c.generate(resolved, root, depth+1)
+	}
+	if v, ok := node["const"]; ok {
+		return v, nil
+	}
+	if enum := schemaEnum(node); len(enum) > 0 {
+		return enum[c.rng.IntN(len(enum))], nil
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if options := schemaList(node, key); len(options) > 0 {
+			return c.generate(options[c.rng.IntN(len(options))], root, depth+1)
+		}
+	}
+	types := schemaTypes(node)
+	if len(types) == 0 {
+		if _, ok := node["properties"]; ok {
+			types = []string{"object"}
+		} else {
+			return nil, fmt.Errorf("cannot generate a value for schema without a type: %v", node)
+		}
+	}
+	switch t := types[c.rng.IntN(len(types))]; t {
+	case "object":
+		return c.generateObject(node, root, depth)
+	case "array":
+		return c.generateArray(node, root, depth)
+	case "string":
+		if format, _ := node["format"].(string); format == "date-time" {
+			return time.Unix(c.rng.Int64N(4e9), 0).UTC().Format(time.RFC3339), nil
+		}
+		n := 1 + c.rng.IntN(4)
+		words := make([]string, n)
+		for i := range words {
+			words[i] = fakeWords[c.rng.IntN(len(fakeWords))]
+		}
+		return strings.Join(words, " "), nil
+	case "integer":
+		lo, hi := c.bounds(node, 0, 100)
+		return lo + c.rng.IntN(hi-lo+1), nil
+	case "number":
+		lo, hi := c.bounds(node, 0, 100)
+		return float64(lo) + c.rng.Float64()*float64(hi-lo), nil
+	case "boolean":
+		return c.rng.IntN(2) == 0, nil
+	case "null":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cannot generate a value for schema type %s", t)
+	}
+}
+
+func (c *fakeClient) generateObject(node, root map[string]any, depth int) (any, error) {
+	obj := make(map[string]any)
+	props, _ := node["properties"].(map[string]any)
+	required := make(map[string]bool)
+	for _, k := range schemaStrings(node, "required") {
+		required[k] = true
+	}
+	for _, k := range sortedKeys(props) {
+		if !required[k] && (depth >= fakeMaxDepth || c.rng.IntN(2) == 0) {
+			continue
+		}
+		propSchema, ok := props[k].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("property %s does not have a schema", k)
+		}
+		v, err := c.generate(propSchema, root, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = v
+	}
+	return obj, nil
+}
+
+func (c *fakeClient) generateArray(node, root map[string]any, depth int) (any, error) {
+	minItems, _ := schemaInt(node, "minItems")
+	maxItems, ok := schemaInt(node, "maxItems")
+	if !ok {
+		maxItems = minItems + 3
+	}
+	if depth >= fakeMaxDepth {
+		maxItems = minItems
+	}
+	n := minItems
+	if maxItems > minItems {
+		n += c.rng.IntN(maxItems - minItems + 1)
+	}
+	items, _ := node["items"].(map[string]any)
+	arr := make([]any, 0, n)
+	for range n {
+		if items == nil {
+			return nil, fmt.Errorf("array does not have an items schema")
+		}
+		v, err := c.generate(items, root, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+// bounds reads the `minimum` and `maximum` of a numeric schema, falling back to defaults.
+func (c *fakeClient) bounds(node map[string]any, defaultMin, defaultMax int) (int, int) {
+	lo, ok := schemaInt(node, "minimum")
+	if !ok {
+		lo = defaultMin
+	}
+	hi, ok := schemaInt(node, "maximum")
+	if !ok {
+		hi = max(defaultMax, lo)
+	}
+	return lo, max(hi, lo)
+}
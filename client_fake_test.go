@@ -0,0 +1,75 @@
+package docqa
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestFakeClientMatchesProtocolSchema(t *testing.T) {
+	types := map[string]Type{"text": testTextType{}, "amount": testAmountType{}}
+	questions := map[string]Question{
+		"title":   {Question: "What is the title?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1, MaxAnswers: 1},
+		"weights": {Question: "What weights are given?", AllowedTypeKeys: []string{"amount", "text"}},
+		"labels":  {Question: "What labels are used?", AllowedTypeKeys: []string{"text"}, MinAnswers: 2, MaxAnswers: 3},
+	}
+	protocols := map[string]Protocol{
+		"plain":      NewBasicProtocol(GetDefaultRoleAndTask(), types),
+		"extensions": NewBasicProtocol(GetDefaultRoleAndTask(), types, WithEvidenceQuotes(), WithSelfRatedConfidence(), WithLineLocalisation()),
+	}
+	for _, name := range sortedKeys(protocols) {
+		qa := protocols[name]
+		schema := qa.Schema(questions)
+		t.Run(name, func(t *testing.T) {
+			for seed := range uint64(200) {
+				resp, _, err := NewFakeClient(seed).GetLLMResponse("", "", schema)
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				if err := ValidateJSON(resp, schema); err != nil {
+					t.Fatalf("seed %d: fake response does not match the schema: %v\n%s", seed, err, resp)
+				}
+				answers, err := qa.ParseResponse(resp)
+				if err != nil {
+					t.Fatalf("seed %d: fake response could not be parsed: %v\n%s", seed, err, resp)
+				}
+				if err := ValidateCardinality(questions, answers); err != nil {
+					t.Fatalf("seed %d: %v\n%s", seed, err, resp)
+				}
+			}
+		})
+	}
+}
+
+func TestFakeClientSeeded(t *testing.T) {
+	schema := newTestTextProtocol().Schema(map[string]Question{
+		"title": {Question: "What is the title?", AllowedTypeKeys: []string{"text"}},
+	})
+	sequence := func(seed uint64) []string {
+		client := NewFakeClient(seed)
+		resps := make([]string, 5)
+		for i := range resps {
+			resp, _, err := client.GetLLMResponse("", "", schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resps[i] = resp
+		}
+		return resps
+	}
+	first, again, other := sequence(1), sequence(1), sequence(2)
+	for i := range first {
+		if first[i] != again[i] {
+			t.Errorf("response %d differs for the same seed: %s vs %s", i, first[i], again[i])
+		}
+	}
+	if slices.Equal(first, other) {
+		t.Errorf("expected different seeds to give different responses, got %v", first)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := NewFakeClient(1).GetLLMResponseContext(ctx, "", "", schema); err != context.Canceled {
+		t.Errorf("expected a cancelled context to fail, got %v", err)
+	}
+}
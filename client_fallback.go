@@ -0,0 +1,60 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// FallbackProvider is a named [Client] in a fallback chain.
+type FallbackProvider struct {
+	Name   string
+	Client Client
+}
+
+type fallbackClient struct {
+	providers []FallbackProvider
+}
+
+// NewFallbackClient creates a [Client] that tries each provider in order, moving on to the next provider
+// when a call fails with an error that [IsRetryable] accepts, or when the response does not pass [ValidateJSON].
+// Any other error is returned immediately.
+//
+// The returned [LLMUsage] sums the usage of every provider that was tried,
+// and its Provider field is set to the name of the provider that answered, or left empty if none did.
+func NewFallbackClient(providers ...FallbackProvider) ContextClient {
+	return &fallbackClient{
+		providers: providers,
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *fallbackClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *fallbackClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	if len(c.providers) == 0 {
+		return "", LLMUsage{}, fmt.Errorf("fallback client has no providers")
+	}
+	var totalUsage LLMUsage
+	var errs []error
+	for _, provider := range c.providers {
+		resp, usage, err := GetLLMResponseWithContext(ctx, provider.Client, systemPrompt, userPrompt, schema)
+		usage.Provider = provider.Name
+		totalUsage = totalUsage.Add(usage)
+		if err == nil {
+			err = ValidateJSON(resp, schema)
+			if err == nil {
+				return resp, totalUsage, nil
+			}
+		} else if !IsRetryable(err) {
+			totalUsage.Provider = ""
+			return "", totalUsage, fmt.Errorf("provider %s failed: %w", provider.Name, err)
+		}
+		errs = append(errs, fmt.Errorf("provider %s failed: %w", provider.Name, err))
+	}
+	totalUsage.Provider = ""
+	return "", totalUsage, errors.Join(errs...)
+}
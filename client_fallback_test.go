@@ -0,0 +1,114 @@
+package docqa
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFallbackClient(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"a": map[string]any{"type": "integer"}},
+		"required":             []any{"a"},
+		"additionalProperties": false,
+	}
+	usage := LLMUsage{InputTokens: 10, OutputTokens: 1}
+	succeed := func(resp string) Client {
+		return stubResponses(usage, resp)
+	}
+	fail := func(err error) Client {
+		return &stubClient{respond: func(int, string, []Message) (string, LLMUsage, error) {
+			return "", usage, err
+		}}
+	}
+	rateLimited := &HTTPStatusError{StatusCode: http.StatusTooManyRequests}
+	unauthorised := &HTTPStatusError{StatusCode: http.StatusUnauthorized}
+	cases := []struct {
+		name      string
+		providers []FallbackProvider
+		resp      string
+		// tried is how many providers should have been called.
+		tried    int
+		provider string
+		wantErr  error
+	}{
+		{
+			name:      "first provider answers",
+			providers: []FallbackProvider{{"a", succeed(`{"a":1}`)}, {"b", succeed(`{"a":2}`)}},
+			resp:      `{"a":1}`,
+			tried:     1,
+			provider:  "a",
+		},
+		{
+			name:      "retryable error falls through",
+			providers: []FallbackProvider{{"a", fail(rateLimited)}, {"b", succeed(`{"a":2}`)}},
+			resp:      `{"a":2}`,
+			tried:     2,
+			provider:  "b",
+		},
+		{
+			name:      "invalid response falls through",
+			providers: []FallbackProvider{{"a", succeed(`{"a":"one"}`)}, {"b", fail(rateLimited)}, {"c", succeed(`{"a":3}`)}},
+			resp:      `{"a":3}`,
+			tried:     3,
+			provider:  "c",
+		},
+		{
+			name:      "non-retryable error stops",
+			providers: []FallbackProvider{{"a", fail(rateLimited)}, {"b", fail(unauthorised)}, {"c", succeed(`{"a":3}`)}},
+			tried:     2,
+			wantErr:   unauthorised,
+		},
+		{
+			name:      "every provider fails",
+			providers: []FallbackProvider{{"a", fail(rateLimited)}, {"b", succeed(`not json`)}},
+			tried:     2,
+			wantErr:   rateLimited,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, gotUsage, err := NewFallbackClient(tc.providers...).GetLLMResponse("system", "user", schema)
+			wantUsage := LLMUsage{InputTokens: 10 * tc.tried, OutputTokens: tc.tried, Provider: tc.provider}
+			if gotUsage != wantUsage {
+				t.Errorf("expected usage %+v, got %+v", wantUsage, gotUsage)
+			}
+			for i, p := range tc.providers {
+				want := 0
+				if i < tc.tried {
+					want = 1
+				}
+				if calls := len(p.Client.(*stubClient).calls); calls != want {
+					t.Errorf("expected provider %s to be called %d times, got %d", p.Name, want, calls)
+				}
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp != tc.resp {
+				t.Errorf("expected %s, got %s", tc.resp, resp)
+			}
+		})
+	}
+
+	t.Run("errors name every provider", func(t *testing.T) {
+		_, _, err := NewFallbackClient(FallbackProvider{"a", fail(rateLimited)}, FallbackProvider{"b", succeed(`{}`)}).GetLLMResponse("system", "user", schema)
+		if err == nil || !strings.Contains(err.Error(), "provider a failed") || !strings.Contains(err.Error(), "provider b failed") {
+			t.Errorf("expected both providers in the error, got %v", err)
+		}
+	})
+
+	t.Run("no providers", func(t *testing.T) {
+		if _, _, err := NewFallbackClient().GetLLMResponse("system", "user", schema); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
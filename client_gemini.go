@@ -0,0 +1,133 @@
+package docqa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type geminiClient struct {
+	key       string
+	model     string
+	cfg       *httpConfig
+	transform SchemaTransform
+}
+
+// NewGeminiClient creates a new client that communicates with the Google Gemini API.
+// Schemas are converted with [GeminiSchemaTransform] before being sent as the `responseSchema`,
+// so any [Protocol] can be used unchanged.
+// The returned client also implements [ConversationClient].
+func NewGeminiClient(key, model string, opts ...ClientOption) ContextClient {
+	return &geminiClient{
+		key:       key,
+		model:     model,
+		cfg:       newHTTPConfig("https://generativelanguage.googleapis.com/v1beta", HeaderAuth("x-goog-api-key"), opts),
+		transform: GeminiSchemaTransform(),
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *geminiClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *geminiClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMConversationResponse(ctx, systemPrompt, userMessages(userPrompt), schema)
+}
+
+// GetLLMConversationResponse implements [ConversationClient].
+func (c *geminiClient) GetLLMConversationResponse(ctx context.Context, systemPrompt string, messages []Message, schema map[string]any) (string, LLMUsage, error) {
+	responseSchema, err := c.transform(schema)
+	if err != nil {
+		return "", LLMUsage{}, fmt.Errorf("failed to convert schema for gemini: %w", err)
+	}
+	contents := make([]map[string]any, 0, len(messages))
+	for _, m := range messages {
+		role := m.Role
+		if role == AssistantRole {
+			// Gemini calls the assistant the model.
+			role = "model"
+		}
+		contents = append(contents, map[string]any{
+			"role":  role,
+			"parts": []map[string]any{{"text": m.Content}},
+		})
+	}
+	bodyMap := map[string]any{
+		"systemInstruction": map[string]any{
+			"parts": []map[string]any{{"text": systemPrompt}},
+		},
+		"contents": contents,
+		"generationConfig": map[string]any{
+			"temperature":      c.cfg.temperature,
+			"responseMimeType": "application/json",
+			"responseSchema":   responseSchema,
+		},
+	}
+	path := fmt.Sprintf("/models/%s:generateContent", url.PathEscape(c.model))
+	req, err := c.cfg.newRequest(ctx, path, c.key, bodyMap)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	respBody, err := c.cfg.do(req)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	respTyped := struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text    string `json:"text"`
+					Thought bool   `json:"thought"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		PromptFeedback struct {
+			BlockReason string `json:"blockReason"`
+		} `json:"promptFeedback"`
+		UsageMetadata struct {
+			PromptTokenCount        int `json:"promptTokenCount"`
+			CandidatesTokenCount    int `json:"candidatesTokenCount"`
+			CachedContentTokenCount int `json:"cachedContentTokenCount"`
+			ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		} `json:"usageMetadata"`
+	}{}
+	err = json.Unmarshal(respBody, &respTyped)
+	if err != nil {
+		return "", LLMUsage{}, newMalformedResponseError(string(respBody), LLMUsage{}, err)
+	}
+	// Gemini reports thinking tokens separately from the candidate tokens, but LLMUsage counts them as part of the output.
+	usage := LLMUsage{
+		InputTokens:       respTyped.UsageMetadata.PromptTokenCount,
+		OutputTokens:      respTyped.UsageMetadata.CandidatesTokenCount + respTyped.UsageMetadata.ThoughtsTokenCount,
+		CachedInputTokens: respTyped.UsageMetadata.CachedContentTokenCount,
+		ReasoningTokens:   respTyped.UsageMetadata.ThoughtsTokenCount,
+	}
+	if reason := respTyped.PromptFeedback.BlockReason; reason != "" {
+		return "", usage, &ContentFilterError{PartialResponse: PartialResponse{Usage: usage}, Reason: reason}
+	}
+	if len(respTyped.Candidates) == 0 {
+		return "", usage, newMalformedResponseError(string(respBody), usage, fmt.Errorf("response had no candidates"))
+	}
+	var content strings.Builder
+	for _, part := range respTyped.Candidates[0].Content.Parts {
+		if !part.Thought {
+			content.WriteString(part.Text)
+		}
+	}
+	partial := PartialResponse{Content: content.String(), Usage: usage}
+	switch reason := respTyped.Candidates[0].FinishReason; reason {
+	case "MAX_TOKENS":
+		return "", usage, &TruncatedError{PartialResponse: partial}
+	case "SAFETY", "PROHIBITED_CONTENT", "BLOCKLIST", "SPII", "RECITATION", "IMAGE_SAFETY":
+		return "", usage, &ContentFilterError{PartialResponse: partial, Reason: reason}
+	}
+	if content.Len() == 0 {
+		return "", usage, newMalformedResponseError(string(respBody), usage, fmt.Errorf("response had no content"))
+	}
+	return content.String(), usage, nil
+}
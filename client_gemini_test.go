@@ -0,0 +1,143 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGeminiClientRequest(t *testing.T) {
+	server, recorded := newRecordingServer(t, http.StatusOK, `{
+		"candidates": [{"content": {"parts": [{"text": "thinking", "thought": true}, {"text": "{\"a\":"}, {"text": "1}"}]}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "cachedContentTokenCount": 4, "thoughtsTokenCount": 3}
+	}`)
+	client := NewGeminiClient("key", "gemini-test", WithBaseURL(server.URL))
+	schema := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"a": map[string]any{"$ref": "#/$defs/answer"},
+		},
+		"required": []any{"a"},
+		"$defs": map[string]any{
+			"answer": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"answer_type": map[string]any{"const": "name"},
+				},
+			},
+		},
+	}
+	resp, usage, err := client.GetLLMResponseContext(context.Background(), "system", "user", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp != `{"a":1}` {
+		t.Errorf("expected thoughts to be skipped and parts joined, got %q", resp)
+	}
+	expectedUsage := LLMUsage{InputTokens: 10, OutputTokens: 8, CachedInputTokens: 4, ReasoningTokens: 3}
+	if usage != expectedUsage {
+		t.Errorf("expected usage %+v, got %+v", expectedUsage, usage)
+	}
+	if recorded.Path != "/models/gemini-test:generateContent" {
+		t.Errorf("unexpected path %q", recorded.Path)
+	}
+	if got := recorded.Header.Get("x-goog-api-key"); got != "key" {
+		t.Errorf("expected api key header, got %q", got)
+	}
+	if got := jsonPath(t, recorded.Body, "contents", 0, "role"); got != "user" {
+		t.Errorf("expected a user turn, got %v", got)
+	}
+
+	expectedSchema := map[string]any{
+		"type": "OBJECT",
+		"properties": map[string]any{
+			"a": map[string]any{
+				"type": "OBJECT",
+				"properties": map[string]any{
+					"answer_type": map[string]any{"enum": []any{"name"}},
+				},
+			},
+		},
+		"required": []any{"a"},
+	}
+	gotSchema := jsonPath(t, recorded.Body, "generationConfig", "responseSchema")
+	if !reflect.DeepEqual(gotSchema, expectedSchema) {
+		t.Errorf("expected response schema %v, got %v", expectedSchema, gotSchema)
+	}
+	if schema["properties"].(map[string]any)["a"].(map[string]any)["$ref"] != "#/$defs/answer" {
+		t.Errorf("the caller's schema was modified")
+	}
+}
+
+func TestGeminiClientFinishReasons(t *testing.T) {
+	usageJSON := `"usageMetadata": {"promptTokenCount": 2, "candidatesTokenCount": 1}`
+	cases := []struct {
+		name     string
+		response string
+		check    func(t *testing.T, err error)
+	}{
+		{
+			name:     "max tokens",
+			response: `{"candidates": [{"content": {"parts": [{"text": "{\"a\""}]}, "finishReason": "MAX_TOKENS"}], ` + usageJSON + `}`,
+			check: func(t *testing.T, err error) {
+				var truncated *TruncatedError
+				if !errors.As(err, &truncated) {
+					t.Fatalf("expected a TruncatedError, got %v", err)
+				}
+				if truncated.Content != `{"a"` {
+					t.Errorf("expected the partial content, got %q", truncated.Content)
+				}
+			},
+		},
+		{
+			name:     "safety",
+			response: `{"candidates": [{"content": {"parts": []}, "finishReason": "SAFETY"}], ` + usageJSON + `}`,
+			check: func(t *testing.T, err error) {
+				var filtered *ContentFilterError
+				if !errors.As(err, &filtered) {
+					t.Fatalf("expected a ContentFilterError, got %v", err)
+				}
+				if filtered.Reason != "SAFETY" {
+					t.Errorf("expected reason SAFETY, got %q", filtered.Reason)
+				}
+			},
+		},
+		{
+			name:     "blocked prompt",
+			response: `{"promptFeedback": {"blockReason": "OTHER"}, ` + usageJSON + `}`,
+			check: func(t *testing.T, err error) {
+				var filtered *ContentFilterError
+				if !errors.As(err, &filtered) || filtered.Reason != "OTHER" {
+					t.Fatalf("expected a ContentFilterError with reason OTHER, got %v", err)
+				}
+			},
+		},
+		{
+			name:     "no content",
+			response: `{"candidates": [{"content": {"parts": []}, "finishReason": "STOP"}], ` + usageJSON + `}`,
+			check: func(t *testing.T, err error) {
+				var malformed *MalformedResponseError
+				if !errors.As(err, &malformed) {
+					t.Fatalf("expected a MalformedResponseError, got %v", err)
+				}
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _ := newRecordingServer(t, http.StatusOK, tc.response)
+			client := NewGeminiClient("key", "gemini-test", WithBaseURL(server.URL))
+			_, usage, err := client.GetLLMResponseContext(context.Background(), "system", "user", map[string]any{"type": "object"})
+			if usage != (LLMUsage{InputTokens: 2, OutputTokens: 1}) {
+				t.Errorf("expected usage to be returned with the error, got %+v", usage)
+			}
+			tc.check(t, err)
+		})
+	}
+}
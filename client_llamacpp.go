@@ -0,0 +1,52 @@
+package docqa
+
+import (
+	"context"
+	"fmt"
+)
+
+type llamaCppClient struct {
+	openAI *openAIClient
+}
+
+// NewLlamaCppClient creates a new client that communicates with a llama.cpp server (by default at `http://localhost:8080/v1`),
+// through its OpenAI-compatible chat completions endpoint.
+// Rather than a json schema, the output is constrained with a GBNF grammar built by [SchemaToGBNF],
+// so it also works with local models and servers that do not support structured outputs.
+// The returned client also implements [ConversationClient].
+func NewLlamaCppClient(model string, opts ...ClientOption) ContextClient {
+	opts = append([]ClientOption{WithBaseURL("http://localhost:8080/v1"), WithAuthScheme(NoAuth())}, opts...)
+	return &llamaCppClient{
+		openAI: newOpenAIClient("", model, opts),
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *llamaCppClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *llamaCppClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMConversationResponse(ctx, systemPrompt, userMessages(userPrompt), schema)
+}
+
+// GetLLMConversationResponse implements [ConversationClient].
+func (c *llamaCppClient) GetLLMConversationResponse(ctx context.Context, systemPrompt string, messages []Message, schema map[string]any) (string, LLMUsage, error) {
+	grammar, err := SchemaToGBNF(schema)
+	if err != nil {
+		return "", LLMUsage{}, fmt.Errorf("failed to convert schema to a grammar: %w", err)
+	}
+	bodyMap := c.openAI.requestBody(systemPrompt, messages, schema)
+	delete(bodyMap, "response_format")
+	bodyMap["grammar"] = grammar
+	req, err := c.openAI.cfg.newRequest(ctx, "/chat/completions", c.openAI.key, bodyMap)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	respBody, err := c.openAI.cfg.do(req)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	return parseOpenAIResponse(respBody)
+}
@@ -0,0 +1,59 @@
+package docqa
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestLlamaCppClientRequest(t *testing.T) {
+	server, recorded := newRecordingServer(t, http.StatusOK, openAITestResponse)
+	client := NewLlamaCppClient("local-model", WithBaseURL(server.URL))
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"a": map[string]any{"type": "integer"}},
+		"required":   []any{"a"},
+	}
+	resp, usage, err := client.GetLLMResponseContext(context.Background(), "system", "user", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp != `{"a":1}` {
+		t.Errorf("unexpected response %q", resp)
+	}
+	if usage.InputTokens != 10 || usage.OutputTokens != 5 {
+		t.Errorf("expected the usage to be parsed, got %+v", usage)
+	}
+	if recorded.Path != "/chat/completions" {
+		t.Errorf("unexpected path %q", recorded.Path)
+	}
+	if got := recorded.Header.Get("Authorization"); got != "" {
+		t.Errorf("expected no Authorization header, got %q", got)
+	}
+	if _, ok := recorded.Body["response_format"]; ok {
+		t.Errorf("expected no response_format, as the grammar replaces it")
+	}
+	expectedGrammar, err := SchemaToGBNF(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recorded.Body["grammar"]; got != expectedGrammar {
+		t.Errorf("expected grammar\n%s\ngot\n%v", expectedGrammar, got)
+	}
+	if got := jsonPath(t, recorded.Body, "messages", 0, "content"); got != "system" {
+		t.Errorf("expected the system prompt as the first message, got %v", got)
+	}
+}
+
+func TestLlamaCppClientUnsupportedSchema(t *testing.T) {
+	server, recorded := newRecordingServer(t, http.StatusOK, openAITestResponse)
+	client := NewLlamaCppClient("local-model", WithBaseURL(server.URL))
+	_, _, err := client.GetLLMResponseContext(context.Background(), "system", "user", map[string]any{"$ref": "#/$defs/missing"})
+	if err == nil {
+		t.Fatal("expected an error for a schema that cannot be converted to a grammar")
+	}
+	if recorded.Method != "" {
+		t.Errorf("expected no request to be sent")
+	}
+}
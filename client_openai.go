@@ -0,0 +1,270 @@
+package docqa
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type openAIClient struct {
+	key   string
+	model string
+	cfg   *httpConfig
+}
+
+// NewOpenAIClient creates a new client that communicates with the OpenAI API.
+// By default it talks to `https://api.openai.com/v1`, but the [ClientOption]s can point it at any
+// OpenAI-compatible chat completions API, such as Azure OpenAI, vLLM, llama.cpp server, or LiteLLM.
+// The returned client also implements [ConversationClient].
+func NewOpenAIClient(key, model string, opts ...ClientOption) ContextClient {
+	return newOpenAIClient(key, model, opts)
+}
+
+// NewOpenAILogprobClient is the same as [NewOpenAIClient], but the returned client can also
+// ask for the log probability of each generated token.
+func NewOpenAILogprobClient(key, model string, opts ...ClientOption) LogprobClient {
+	return newOpenAIClient(key, model, opts)
+}
+
+// NewOpenAIStreamingClient is the same as [NewOpenAIClient], but the returned client can also
+// stream its responses over server-sent events.
+func NewOpenAIStreamingClient(key, model string, opts ...ClientOption) StreamingClient {
+	return newOpenAIClient(key, model, opts)
+}
+
+func newOpenAIClient(key, model string, opts []ClientOption) *openAIClient {
+	return &openAIClient{
+		key:   key,
+		model: model,
+		cfg:   newHTTPConfig("https://api.openai.com/v1", BearerAuth(), opts),
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *openAIClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *openAIClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMConversationResponse(ctx, systemPrompt, userMessages(userPrompt), schema)
+}
+
+// GetLLMConversationResponse implements [ConversationClient].
+func (c *openAIClient) GetLLMConversationResponse(ctx context.Context, systemPrompt string, messages []Message, schema map[string]any) (string, LLMUsage, error) {
+	req, err := c.cfg.newRequest(ctx, "/chat/completions", c.key, c.requestBody(systemPrompt, messages, schema))
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	respBody, err := c.cfg.do(req)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	return parseOpenAIResponse(respBody)
+}
+
+// GetLLMResponseLogprobs implements [LogprobClient].
+func (c *openAIClient) GetLLMResponseLogprobs(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, []TokenLogprob, LLMUsage, error) {
+	bodyMap := c.requestBody(systemPrompt, userMessages(userPrompt), schema)
+	bodyMap["logprobs"] = true
+	req, err := c.cfg.newRequest(ctx, "/chat/completions", c.key, bodyMap)
+	if err != nil {
+		return "", nil, LLMUsage{}, err
+	}
+	respBody, err := c.cfg.do(req)
+	if err != nil {
+		return "", nil, LLMUsage{}, err
+	}
+	return parseOpenAIResponseLogprobs(respBody)
+}
+
+// parseOpenAIResponse extracts the content and usage from a chat completions response body.
+func parseOpenAIResponse(respBody []byte) (string, LLMUsage, error) {
+	content, _, usage, err := parseOpenAIResponseLogprobs(respBody)
+	return content, usage, err
+}
+
+// parseOpenAIResponseLogprobs extracts the content, token logprobs (if any) and usage from a chat completions response body.
+func parseOpenAIResponseLogprobs(respBody []byte) (string, []TokenLogprob, LLMUsage, error) {
+	respTyped := struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+				Refusal string `json:"refusal"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+			Logprobs     *struct {
+				Content []struct {
+					Token   string  `json:"token"`
+					Logprob float64 `json:"logprob"`
+					Bytes   []int   `json:"bytes"`
+				} `json:"content"`
+			} `json:"logprobs"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}{}
+	err := json.Unmarshal(respBody, &respTyped)
+	if err != nil {
+		return "", nil, LLMUsage{}, newMalformedResponseError(string(respBody), LLMUsage{}, err)
+	}
+	usage := respTyped.Usage.toLLMUsage()
+	if len(respTyped.Choices) == 0 {
+		return "", nil, usage, newMalformedResponseError(string(respBody), usage, fmt.Errorf("response had no choices"))
+	}
+	choice := respTyped.Choices[0]
+	if err := openAIFinishError(choice.Message.Content, choice.Message.Refusal, choice.FinishReason, usage); err != nil {
+		return "", nil, usage, err
+	}
+	if choice.Message.Content == "" {
+		return "", nil, usage, newMalformedResponseError(string(respBody), usage, fmt.Errorf("response had no content"))
+	}
+	var logprobs []TokenLogprob
+	if choice.Logprobs != nil {
+		logprobs = make([]TokenLogprob, len(choice.Logprobs.Content))
+		for i, lp := range choice.Logprobs.Content {
+			logprobs[i] = TokenLogprob{Token: lp.Token, Logprob: lp.Logprob}
+			// The bytes are a list of ints, rather than the base64 string json uses for []byte.
+			if lp.Bytes != nil {
+				logprobs[i].Bytes = make([]byte, len(lp.Bytes))
+				for j, b := range lp.Bytes {
+					logprobs[i].Bytes[j] = byte(b)
+				}
+			}
+		}
+	}
+	return choice.Message.Content, logprobs, usage, nil
+}
+
+// openAIFinishError converts refusals and abnormal finish reasons into typed errors.
+func openAIFinishError(content, refusal, finishReason string, usage LLMUsage) error {
+	partial := PartialResponse{Content: content, Usage: usage}
+	switch {
+	case refusal != "":
+		return &RefusalError{PartialResponse: partial, Refusal: refusal}
+	case finishReason == "length":
+		return &TruncatedError{PartialResponse: partial}
+	case finishReason == "content_filter":
+		return &ContentFilterError{PartialResponse: partial}
+	default:
+		return nil
+	}
+}
+
+// StreamLLMResponse implements [StreamingClient].
+func (c *openAIClient) StreamLLMResponse(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any, onChunk func(string) error) (string, LLMUsage, error) {
+	bodyMap := c.requestBody(systemPrompt, userMessages(userPrompt), schema)
+	bodyMap["stream"] = true
+	bodyMap["stream_options"] = map[string]any{"include_usage": true}
+	req, err := c.cfg.newRequest(ctx, "/chat/completions", c.key, bodyMap)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := c.cfg.send(req)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	defer resp.Body.Close()
+
+	var content, refusal strings.Builder
+	var usage LLMUsage
+	finishReason := ""
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			// Blank lines separate events, and we have no use for comments, ids or event names.
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		event := struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+					Refusal string `json:"refusal"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *openAIUsage `json:"usage"`
+		}{}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return "", usage, newMalformedResponseError(content.String(), usage, fmt.Errorf("failed to parse stream event %s: %w", data, err))
+		}
+		if event.Usage != nil {
+			usage = event.Usage.toLLMUsage()
+		}
+		if len(event.Choices) == 0 {
+			continue
+		}
+		if event.Choices[0].FinishReason != "" {
+			finishReason = event.Choices[0].FinishReason
+		}
+		refusal.WriteString(event.Choices[0].Delta.Refusal)
+		chunk := event.Choices[0].Delta.Content
+		if chunk == "" {
+			continue
+		}
+		content.WriteString(chunk)
+		if err := onChunk(chunk); err != nil {
+			return "", usage, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", usage, err
+	}
+	if err := openAIFinishError(content.String(), refusal.String(), finishReason, usage); err != nil {
+		return "", usage, err
+	}
+	if content.Len() == 0 {
+		return "", usage, newMalformedResponseError("", usage, fmt.Errorf("stream did not contain any content"))
+	}
+	return content.String(), usage, nil
+}
+
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u openAIUsage) toLLMUsage() LLMUsage {
+	return LLMUsage{
+		InputTokens:       u.PromptTokens,
+		OutputTokens:      u.CompletionTokens,
+		CachedInputTokens: u.PromptTokensDetails.CachedTokens,
+		ReasoningTokens:   u.CompletionTokensDetails.ReasoningTokens,
+	}
+}
+
+func (c *openAIClient) requestBody(systemPrompt string, messages []Message, schema map[string]any) map[string]any {
+	chatMessages := []map[string]any{
+		{
+			"role":    "system",
+			"content": systemPrompt,
+		},
+	}
+	for _, m := range messages {
+		chatMessages = append(chatMessages, map[string]any{
+			"role":    m.Role,
+			"content": m.Content,
+		})
+	}
+	return map[string]any{
+		"model":           c.model,
+		"temperature":     c.cfg.temperature,
+		"response_format": wrapOpenAISchema(schema),
+		"messages":        chatMessages,
+	}
+}
@@ -0,0 +1,186 @@
+package docqa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ClientOption configures how an HTTP-backed [Client] communicates with its API.
+type ClientOption func(*httpConfig)
+
+// AuthScheme attaches the API key to an outgoing request.
+type AuthScheme func(req *http.Request, key string)
+
+type httpConfig struct {
+	baseURL     string
+	httpClient  *http.Client
+	headers     http.Header
+	queryParams url.Values
+	auth        AuthScheme
+	temperature float64
+	// batchEndpoint is the endpoint batch requests are sent to, see [WithBatchEndpoint].
+	batchEndpoint string
+}
+
+func newHTTPConfig(defaultBaseURL string, defaultAuth AuthScheme, opts []ClientOption) *httpConfig {
+	cfg := &httpConfig{
+		baseURL:     defaultBaseURL,
+		httpClient:  http.DefaultClient,
+		headers:     make(http.Header),
+		queryParams: make(url.Values),
+		auth:        defaultAuth,
+		temperature: 0.1,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithBaseURL overrides the base URL of the API, for example to point at a proxy or a local test server.
+// Endpoint paths (such as `/chat/completions`) are appended to this URL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *httpConfig) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithBatchEndpoint sets the endpoint path that an [OpenAIBatcher] asks the batch to send its requests to,
+// which by default is the path of the base URL followed by `/chat/completions` (so `/v1/chat/completions` for OpenAI).
+// Azure OpenAI expects `/chat/completions` regardless of its base URL.
+func WithBatchEndpoint(path string) ClientOption {
+	return func(c *httpConfig) {
+		c.batchEndpoint = path
+	}
+}
+
+// WithHTTPClient sets the [http.Client] used to send requests (defaults to [http.DefaultClient]).
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *httpConfig) {
+		c.httpClient = client
+	}
+}
+
+// WithHeader adds an extra header to every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *httpConfig) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithQueryParam adds an extra query parameter to every request.
+func WithQueryParam(key, value string) ClientOption {
+	return func(c *httpConfig) {
+		c.queryParams.Add(key, value)
+	}
+}
+
+// WithAPIVersion sets the `api-version` query parameter, as required by Azure OpenAI.
+func WithAPIVersion(version string) ClientOption {
+	return func(c *httpConfig) {
+		c.queryParams.Set("api-version", version)
+	}
+}
+
+// WithAuthScheme overrides how the API key is attached to each request.
+func WithAuthScheme(auth AuthScheme) ClientOption {
+	return func(c *httpConfig) {
+		c.auth = auth
+	}
+}
+
+// WithTemperature sets the sampling temperature (defaults to 0.1).
+func WithTemperature(temperature float64) ClientOption {
+	return func(c *httpConfig) {
+		c.temperature = temperature
+	}
+}
+
+// BearerAuth sends the key as an `Authorization: Bearer <key>` header.
+func BearerAuth() AuthScheme {
+	return func(req *http.Request, key string) {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	}
+}
+
+// HeaderAuth sends the key verbatim in the named header, for example `api-key` for Azure OpenAI.
+func HeaderAuth(header string) AuthScheme {
+	return func(req *http.Request, key string) {
+		req.Header.Set(header, key)
+	}
+}
+
+// NoAuth does not send the key at all, which is useful for local servers.
+func NoAuth() AuthScheme {
+	return func(req *http.Request, key string) {}
+}
+
+// newRequest builds an authenticated json POST request to the given path relative to the base URL.
+func (c *httpConfig) newRequest(ctx context.Context, path string, key string, bodyMap map[string]any) (*http.Request, error) {
+	body, err := json.Marshal(bodyMap)
+	if err != nil {
+		return nil, err
+	}
+	return c.newRawRequest(ctx, "POST", path, key, bytes.NewBuffer(body), "application/json")
+}
+
+// newRawRequest builds an authenticated request to the given path relative to the base URL.
+// If body is nil, no content type is set.
+func (c *httpConfig) newRawRequest(ctx context.Context, method string, path string, key string, body io.Reader, contentType string) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(c.queryParams) > 0 {
+		u += "?" + c.queryParams.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Add("Content-Type", contentType)
+	}
+	for k, vs := range c.headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if c.auth != nil {
+		c.auth(req, key)
+	}
+	return req, nil
+}
+
+// send sends the request, returning an [*HTTPStatusError] if the status code is not 2xx.
+// On success, the caller must close the response body.
+func (c *httpConfig) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       string(respBody),
+		}
+	}
+	return resp, nil
+}
+
+// do sends the request and reads the whole response body,
+// returning an [*HTTPStatusError] if the status code is not 2xx.
+func (c *httpConfig) do(req *http.Request) ([]byte, error) {
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
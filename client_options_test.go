@@ -0,0 +1,153 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const openAITestResponse = `{
+	"choices": [{"message": {"content": "{\"a\":1}"}, "finish_reason": "stop"}],
+	"usage": {"prompt_tokens": 10, "completion_tokens": 5, "prompt_tokens_details": {"cached_tokens": 4}, "completion_tokens_details": {"reasoning_tokens": 2}}
+}`
+
+func TestOpenAIClientOptions(t *testing.T) {
+	cases := []struct {
+		name        string
+		basePath    string
+		opts        []ClientOption
+		path        string
+		header      http.Header
+		query       map[string][]string
+		absent      []string
+		temperature float64
+	}{
+		{
+			name:        "defaults",
+			path:        "/chat/completions",
+			header:      http.Header{"Authorization": {"Bearer key"}},
+			query:       map[string][]string{},
+			temperature: 0.1,
+		},
+		{
+			name:     "azure",
+			basePath: "/openai/deployments/gpt/",
+			opts: []ClientOption{
+				WithAPIVersion("2024-10-21"),
+				WithAuthScheme(HeaderAuth("api-key")),
+			},
+			path:        "/openai/deployments/gpt/chat/completions",
+			header:      http.Header{"Api-Key": {"key"}},
+			query:       map[string][]string{"api-version": {"2024-10-21"}},
+			absent:      []string{"Authorization"},
+			temperature: 0.1,
+		},
+		{
+			name: "proxy",
+			opts: []ClientOption{
+				WithHeader("X-Team", "docs"),
+				WithHeader("X-Team", "search"),
+				WithQueryParam("trace", "1"),
+				WithAuthScheme(NoAuth()),
+				WithTemperature(0),
+			},
+			path:        "/chat/completions",
+			header:      http.Header{"X-Team": {"docs", "search"}},
+			query:       map[string][]string{"trace": {"1"}},
+			absent:      []string{"Authorization"},
+			temperature: 0,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, recorded := newRecordingServer(t, http.StatusOK, openAITestResponse)
+			opts := append([]ClientOption{WithBaseURL(server.URL + tc.basePath)}, tc.opts...)
+			client := NewOpenAIClient("key", "gpt-test", opts...)
+			resp, usage, err := client.GetLLMResponseContext(context.Background(), "system", "user", map[string]any{"type": "object"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp != `{"a":1}` {
+				t.Errorf("unexpected response %q", resp)
+			}
+			expectedUsage := LLMUsage{InputTokens: 10, OutputTokens: 5, CachedInputTokens: 4, ReasoningTokens: 2}
+			if usage != expectedUsage {
+				t.Errorf("expected usage %+v, got %+v", expectedUsage, usage)
+			}
+			if recorded.Method != http.MethodPost || recorded.Path != tc.path {
+				t.Errorf("expected POST %s, got %s %s", tc.path, recorded.Method, recorded.Path)
+			}
+			for k, vs := range tc.header {
+				if got := recorded.Header.Values(k); !reflect.DeepEqual(got, vs) {
+					t.Errorf("expected header %s to be %v, got %v", k, vs, got)
+				}
+			}
+			for _, k := range tc.absent {
+				if got := recorded.Header.Get(k); got != "" {
+					t.Errorf("expected no %s header, got %q", k, got)
+				}
+			}
+			if !reflect.DeepEqual(recorded.Query, tc.query) {
+				t.Errorf("expected query %v, got %v", tc.query, recorded.Query)
+			}
+			if got := recorded.Body["temperature"]; got != tc.temperature {
+				t.Errorf("expected temperature %v, got %v", tc.temperature, got)
+			}
+			if got := recorded.Body["model"]; got != "gpt-test" {
+				t.Errorf("expected model gpt-test, got %v", got)
+			}
+		})
+	}
+}
+
+func TestOpenAIClientWithHTTPClient(t *testing.T) {
+	server, _ := newRecordingServer(t, http.StatusOK, openAITestResponse)
+	used := false
+	httpClient := &http.Client{
+		Timeout: time.Second,
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			used = true
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	client := NewOpenAIClient("key", "gpt-test", WithBaseURL(server.URL), WithHTTPClient(httpClient))
+	if _, _, err := client.GetLLMResponseContext(context.Background(), "system", "user", map[string]any{"type": "object"}); err != nil {
+		t.Fatal(err)
+	}
+	if !used {
+		t.Errorf("expected the request to be sent with the given http client")
+	}
+}
+
+func TestOpenAIClientFinishReasons(t *testing.T) {
+	cases := []struct {
+		name     string
+		response string
+		target   any
+	}{
+		{"length", `{"choices": [{"message": {"content": "{"}, "finish_reason": "length"}]}`, new(*TruncatedError)},
+		{"content filter", `{"choices": [{"message": {"content": ""}, "finish_reason": "content_filter"}]}`, new(*ContentFilterError)},
+		{"refusal", `{"choices": [{"message": {"refusal": "no"}, "finish_reason": "stop"}]}`, new(*RefusalError)},
+		{"no choices", `{"choices": []}`, new(*MalformedResponseError)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, _ := newRecordingServer(t, http.StatusOK, tc.response)
+			client := NewOpenAIClient("key", "gpt-test", WithBaseURL(server.URL))
+			_, _, err := client.GetLLMResponseContext(context.Background(), "system", "user", map[string]any{"type": "object"})
+			if !errors.As(err, tc.target) {
+				t.Errorf("expected a %T, got %v", tc.target, err)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,172 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryOption configures a [Client] created with [NewRetryClient].
+type RetryOption func(*retryClient)
+
+type retryClient struct {
+	client      Client
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	budget      time.Duration
+	retryable   func(error) bool
+}
+
+// NewRetryClient wraps a [Client] so that transient failures are retried with jittered exponential backoff.
+// Rate limit hints from the `Retry-After`, `retry-after-ms` and `x-ratelimit-reset-*` headers are honoured when present,
+// but are capped at the same maximum delay as the backoff.
+// The returned usage is the sum of the usage of every attempt.
+//
+// By default, a call is attempted up to 4 times, with backoff starting at 1s and capped at 1m,
+// and errors are classified with [IsRetryable].
+func NewRetryClient(client Client, opts ...RetryOption) ContextClient {
+	c := &retryClient{
+		client:      client,
+		maxAttempts: 4,
+		baseDelay:   time.Second,
+		maxDelay:    time.Minute,
+		retryable:   IsRetryable,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithMaxAttempts sets the maximum number of attempts per call, including the first.
+func WithMaxAttempts(n int) RetryOption {
+	return func(c *retryClient) {
+		c.maxAttempts = max(n, 1)
+	}
+}
+
+// WithBackoff sets the initial delay between attempts, and the cap that neither the exponential backoff nor a server's rate limit hint can exceed.
+func WithBackoff(base, maxDelay time.Duration) RetryOption {
+	return func(c *retryClient) {
+		c.baseDelay = base
+		c.maxDelay = maxDelay
+	}
+}
+
+// WithRetryBudget sets the maximum total time to spend waiting between attempts of a single call.
+// If the next wait would exceed the budget, the last error is returned instead. Zero means no budget.
+func WithRetryBudget(budget time.Duration) RetryOption {
+	return func(c *retryClient) {
+		c.budget = budget
+	}
+}
+
+// WithRetryClassifier overrides how errors are classified as retryable.
+func WithRetryClassifier(retryable func(error) bool) RetryOption {
+	return func(c *retryClient) {
+		c.retryable = retryable
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *retryClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *retryClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	var totalUsage LLMUsage
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		resp, usage, err := GetLLMResponseWithContext(ctx, c.client, systemPrompt, userPrompt, schema)
+		totalUsage = totalUsage.Add(usage)
+		if err == nil {
+			return resp, totalUsage, nil
+		}
+		if attempt+1 >= c.maxAttempts || !c.retryable(err) {
+			return "", totalUsage, err
+		}
+		delay := c.backoff(attempt)
+		if hint, ok := retryHint(err); ok {
+			delay = min(hint, c.maxDelay)
+		}
+		if c.budget > 0 && waited+delay > c.budget {
+			return "", totalUsage, err
+		}
+		waited += delay
+		if err := sleepContext(ctx, delay); err != nil {
+			return "", totalUsage, err
+		}
+	}
+}
+
+// backoff picks a random delay in [0, min(maxDelay, baseDelay * 2^attempt)] (full jitter).
+func (c *retryClient) backoff(attempt int) time.Duration {
+	ceiling := c.maxDelay
+	if attempt < 32 {
+		ceiling = min(c.maxDelay, c.baseDelay<<attempt)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// retryHint extracts the server-requested wait from the headers of an [*HTTPStatusError].
+func retryHint(err error) (time.Duration, bool) {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.Header == nil {
+		return 0, false
+	}
+	h := statusErr.Header
+	if ms, err := strconv.Atoi(h.Get("retry-after-ms")); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	if ra := h.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if at, err := http.ParseTime(ra); err == nil {
+			return max(time.Until(at), 0), true
+		}
+	}
+	if statusErr.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	// OpenAI reports how long until each rate limit resets, e.g. `6m0s` or `250ms`.
+	// Only wait on the limits that are actually exhausted, or on all of them if unsure.
+	var hint time.Duration
+	found := false
+	for _, limit := range []string{"requests", "tokens"} {
+		reset, err := time.ParseDuration(h.Get("x-ratelimit-reset-" + limit))
+		if err != nil {
+			continue
+		}
+		remaining := strings.TrimSpace(h.Get("x-ratelimit-remaining-" + limit))
+		if remaining != "" && remaining != "0" {
+			continue
+		}
+		hint = max(hint, reset)
+		found = true
+	}
+	return hint, found
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,253 @@
+package docqa
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryHint(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		header http.Header
+		hint   time.Duration
+		ok     bool
+	}{
+		{"no headers", 429, nil, 0, false},
+		{"retry-after-ms", 503, http.Header{"Retry-After-Ms": {"250"}}, 250 * time.Millisecond, true},
+		{"retry-after seconds", 503, http.Header{"Retry-After": {"3"}}, 3 * time.Second, true},
+		{"retry-after-ms takes priority", 429, http.Header{"Retry-After-Ms": {"10"}, "Retry-After": {"3"}}, 10 * time.Millisecond, true},
+		{"invalid retry-after", 429, http.Header{"Retry-After": {"soon"}}, 0, false},
+		{"exhausted rate limit", 429, http.Header{"X-Ratelimit-Reset-Tokens": {"6m0s"}, "X-Ratelimit-Remaining-Tokens": {"0"}}, 6 * time.Minute, true},
+		{"longest exhausted rate limit", 429, http.Header{"X-Ratelimit-Reset-Tokens": {"2s"}, "X-Ratelimit-Reset-Requests": {"5s"}}, 5 * time.Second, true},
+		{"rate limit that is not exhausted", 429, http.Header{"X-Ratelimit-Reset-Requests": {"5s"}, "X-Ratelimit-Remaining-Requests": {"12"}, "X-Ratelimit-Reset-Tokens": {"1s"}}, time.Second, true},
+		{"rate limit headers without a 429", 500, http.Header{"X-Ratelimit-Reset-Tokens": {"6m0s"}}, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &HTTPStatusError{StatusCode: tc.status, Header: tc.header})
+			hint, ok := retryHint(err)
+			if hint != tc.hint || ok != tc.ok {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tc.hint, tc.ok, hint, ok)
+			}
+		})
+	}
+
+	t.Run("not a status error", func(t *testing.T) {
+		if _, ok := retryHint(errors.New("boom")); ok {
+			t.Errorf("expected no hint")
+		}
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://example.com/chat/completions", Err: err}
+	}
+	dialErr := func(errno syscall.Errno) error {
+		return urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)})
+	}
+	cases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"nil", nil, false},
+		{"cancelled", fmt.Errorf("wrapped: %w", context.Canceled), false},
+		{"caller deadline", context.DeadlineExceeded, false},
+		{"caller deadline during a request", urlErr(context.DeadlineExceeded), false},
+		{"rate limited", &HTTPStatusError{StatusCode: 429}, true},
+		{"server error", &HTTPStatusError{StatusCode: 503}, true},
+		{"bad request", &HTTPStatusError{StatusCode: 400}, false},
+		{"unauthorised", &HTTPStatusError{StatusCode: 401}, false},
+		{"connection refused", dialErr(syscall.ECONNREFUSED), true},
+		{"connection reset", urlErr(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"broken pipe", urlErr(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}), true},
+		{"dial timeout", urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}), true},
+		{"unexpected eof", urlErr(io.ErrUnexpectedEOF), true},
+		{"closed before responding", urlErr(io.EOF), true},
+		{"host unreachable", dialErr(syscall.EHOSTUNREACH), false},
+		{"unsupported scheme", urlErr(errors.New(`unsupported protocol scheme "ftp"`)), false},
+		{"untrusted certificate", urlErr(x509.UnknownAuthorityError{}), false},
+		{"malformed response", newMalformedResponseError("{", LLMUsage{}, errors.New("bad json")), false},
+		{"other error", errors.New("boom"), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.retryable {
+				t.Errorf("expected %v for %v", tc.retryable, tc.err)
+			}
+		})
+	}
+
+	t.Run("http client timeout", func(t *testing.T) {
+		// The server does not answer in time, so the request is ended by the client's timeout.
+		// Reading the body lets the server notice when the client hangs up.
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}))
+		defer server.Close()
+		slow := &http.Client{Timeout: 50 * time.Millisecond}
+		client := NewOpenAIClient("key", "gpt-test", WithBaseURL(server.URL), WithHTTPClient(slow))
+		_, _, err := client.GetLLMResponseContext(context.Background(), "system", "user", nil)
+		if err == nil || !IsRetryable(err) {
+			t.Errorf("expected a retryable timeout, got %v", err)
+		}
+	})
+}
+
+func TestRetryClient(t *testing.T) {
+	retryableErr := &HTTPStatusError{StatusCode: 503}
+	cases := []struct {
+		name  string
+		errs  []error
+		opts  []RetryOption
+		calls int
+		usage LLMUsage
+		// status is the status code of the error that should be returned, or 0 for success.
+		status   int
+		maxSleep time.Duration
+	}{
+		{
+			name:  "success after retryable errors",
+			errs:  []error{retryableErr, retryableErr, nil},
+			calls: 3,
+			usage: LLMUsage{InputTokens: 3, OutputTokens: 3},
+		},
+		{
+			name:   "non-retryable error is returned straight away",
+			errs:   []error{&HTTPStatusError{StatusCode: 400}, nil},
+			calls:  1,
+			usage:  LLMUsage{InputTokens: 1, OutputTokens: 1},
+			status: 400,
+		},
+		{
+			name:   "gives up after max attempts",
+			errs:   []error{retryableErr, retryableErr, retryableErr, nil},
+			opts:   []RetryOption{WithMaxAttempts(2)},
+			calls:  2,
+			usage:  LLMUsage{InputTokens: 2, OutputTokens: 2},
+			status: 503,
+		},
+		{
+			name:  "custom classifier",
+			errs:  []error{errors.New("flaky"), nil},
+			opts:  []RetryOption{WithRetryClassifier(func(err error) bool { return err.Error() == "flaky" })},
+			calls: 2,
+			usage: LLMUsage{InputTokens: 2, OutputTokens: 2},
+		},
+		{
+			name: "server hint is capped at the maximum delay",
+			errs: []error{
+				&HTTPStatusError{StatusCode: 429, Header: http.Header{"X-Ratelimit-Reset-Tokens": {"6m0s"}, "X-Ratelimit-Remaining-Tokens": {"0"}}},
+				nil,
+			},
+			calls:    2,
+			usage:    LLMUsage{InputTokens: 2, OutputTokens: 2},
+			maxSleep: time.Second,
+		},
+		{
+			name: "budget is not exceeded",
+			errs: []error{
+				&HTTPStatusError{StatusCode: 429, Header: http.Header{"Retry-After-Ms": {"50"}}},
+				nil,
+			},
+			opts:     []RetryOption{WithBackoff(time.Millisecond, time.Minute), WithRetryBudget(10 * time.Millisecond)},
+			calls:    1,
+			usage:    LLMUsage{InputTokens: 1, OutputTokens: 1},
+			status:   429,
+			maxSleep: 40 * time.Millisecond,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &stubClient{respond: func(call int, _ string, _ []Message) (string, LLMUsage, error) {
+				// Failed attempts still cost tokens, which must be included in the total.
+				usage := LLMUsage{InputTokens: 1, OutputTokens: 1}
+				if err := tc.errs[call]; err != nil {
+					return "", usage, err
+				}
+				return "ok", usage, nil
+			}}
+			opts := append([]RetryOption{WithBackoff(time.Millisecond, 5*time.Millisecond)}, tc.opts...)
+			start := time.Now()
+			resp, usage, err := NewRetryClient(inner, opts...).GetLLMResponseContext(context.Background(), "system", "user", nil)
+			elapsed := time.Since(start)
+
+			if len(inner.calls) != tc.calls {
+				t.Errorf("expected %d calls, got %d", tc.calls, len(inner.calls))
+			}
+			if usage != tc.usage {
+				t.Errorf("expected usage %+v, got %+v", tc.usage, usage)
+			}
+			var statusErr *HTTPStatusError
+			if tc.status == 0 {
+				if err != nil || resp != "ok" {
+					t.Errorf("expected success, got %q, %v", resp, err)
+				}
+			} else if !errors.As(err, &statusErr) || statusErr.StatusCode != tc.status {
+				t.Errorf("expected a %d status error, got %v", tc.status, err)
+			}
+			if tc.maxSleep > 0 && elapsed > tc.maxSleep {
+				t.Errorf("expected to finish within %v, took %v", tc.maxSleep, elapsed)
+			}
+		})
+	}
+}
+
+func TestRetryClientAgainstServer(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"error": "slow down"}`)
+			return
+		}
+		io.WriteString(w, openAITestResponse)
+	}))
+	defer server.Close()
+	client := NewRetryClient(NewOpenAIClient("key", "gpt-test", WithBaseURL(server.URL)))
+	start := time.Now()
+	resp, usage, err := client.GetLLMResponseContext(context.Background(), "system", "user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != `{"a":1}` || attempts != 3 {
+		t.Errorf("expected success on the third attempt, got %q after %d", resp, attempts)
+	}
+	if usage.InputTokens != 10 {
+		t.Errorf("expected the usage of the successful attempt, got %+v", usage)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the server's short hint to be used instead of the 1s backoff, took %v", elapsed)
+	}
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		failing := &stubClient{respond: func(int, string, []Message) (string, LLMUsage, error) {
+			return "", LLMUsage{}, &HTTPStatusError{StatusCode: 503}
+		}}
+		_, _, err := NewRetryClient(failing, WithBackoff(time.Minute, time.Minute)).GetLLMResponseContext(ctx, "system", "user", nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the context error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package docqa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// recordedRequest is a request received by a server made with newRecordingServer.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  map[string][]string
+	Header http.Header
+	Body   map[string]any
+}
+
+// newRecordingServer starts a server that records the last request it was sent and replies with the given status and body.
+func newRecordingServer(t *testing.T, status int, response string) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	recorded := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		*recorded = recordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+		}
+		if err := json.Unmarshal(body, &recorded.Body); err != nil {
+			t.Errorf("request body was not a json object: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, recorded
+}
+
+// jsonPath follows the keys (or, for arrays, indices) through a decoded json value, failing the test if any is missing.
+func jsonPath(t *testing.T, value any, path ...any) any {
+	t.Helper()
+	for _, p := range path {
+		switch p := p.(type) {
+		case string:
+			obj, ok := value.(map[string]any)
+			if !ok {
+				t.Fatalf("expected an object at %v, got %T", p, value)
+			}
+			if value, ok = obj[p]; !ok {
+				t.Fatalf("missing key %q", p)
+			}
+		case int:
+			arr, ok := value.([]any)
+			if !ok || p >= len(arr) {
+				t.Fatalf("expected an array with index %d, got %v", p, value)
+			}
+			value = arr[p]
+		}
+	}
+	return value
+}
+
+// testTextEntity is a minimal entity for tests, as the root package cannot import qatypes.
+type testTextEntity struct {
+	EntityAttributes
+	Text string
+}
+
+func (e *testTextEntity) MakeContent() (map[string]any, error) {
+	return map[string]any{"text": e.Text}, nil
+}
+
+func (e *testTextEntity) LoadContent(content map[string]any) error {
+	text, ok := content["text"].(string)
+	if !ok {
+		return fmt.Errorf("text must be a string")
+	}
+	e.Text = text
+	return nil
+}
+
+type testTextType struct{}
+
+func (testTextType) Parse(value map[string]any) (Entity, error) {
+	e := &testTextEntity{}
+	return e, e.LoadContent(value)
+}
+
+func (testTextType) Instructions() TypeInstructions {
+	return TypeInstructions{OneLiner: "A piece of text."}
+}
+
+func (testTextType) SchemaProperties() map[string]any {
+	return map[string]any{"text": map[string]any{"type": "string"}}
+}
+
+// testAmountType is a second test type, with a number and an enum, so schemas have more than strings in them.
+type testAmountType struct{}
+
+func (testAmountType) Parse(value map[string]any) (Entity, error) {
+	amount, ok := value["value"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("value must be a number")
+	}
+	unit, ok := value["unit"].(string)
+	if !ok || (unit != "kg" && unit != "g") {
+		return nil, fmt.Errorf("unit must be kg or g")
+	}
+	return &testTextEntity{Text: fmt.Sprintf("%g%s", amount, unit)}, nil
+}
+
+func (testAmountType) Instructions() TypeInstructions {
+	return TypeInstructions{OneLiner: "A weight.", Details: []string{"Use the unit given in the document"}}
+}
+
+func (testAmountType) SchemaProperties() map[string]any {
+	return map[string]any{
+		"value": map[string]any{"type": "number"},
+		"unit":  map[string]any{"type": "string", "enum": []any{"kg", "g"}},
+	}
+}
+
+// newTestTextProtocol creates a basic protocol whose only type is a testTextType called text.
+func newTestTextProtocol(opts ...BasicProtocolOption) Protocol {
+	return NewBasicProtocol(GetDefaultRoleAndTask(), map[string]Type{"text": testTextType{}}, opts...)
+}
+
+// stubClient is a [ConversationClient] that answers with a function of the conversation, recording every call.
+type stubClient struct {
+	respond func(call int, systemPrompt string, messages []Message) (string, LLMUsage, error)
+	mu      sync.Mutex
+	calls   [][]Message
+}
+
+func (c *stubClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+func (c *stubClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMConversationResponse(ctx, systemPrompt, userMessages(userPrompt), schema)
+}
+
+func (c *stubClient) GetLLMConversationResponse(ctx context.Context, systemPrompt string, messages []Message, schema map[string]any) (string, LLMUsage, error) {
+	c.mu.Lock()
+	call := len(c.calls)
+	c.calls = append(c.calls, append([]Message(nil), messages...))
+	c.mu.Unlock()
+	return c.respond(call, systemPrompt, messages)
+}
+
+// stubResponses builds a stubClient that gives each response in turn, with the given usage, failing if it runs out.
+func stubResponses(usage LLMUsage, responses ...string) *stubClient {
+	return &stubClient{respond: func(call int, _ string, _ []Message) (string, LLMUsage, error) {
+		if call >= len(responses) {
+			return "", LLMUsage{}, fmt.Errorf("unexpected call %d", call)
+		}
+		return responses[call], usage, nil
+	}}
+}
@@ -0,0 +1,119 @@
+package docqa
+
+import (
+	"fmt"
+	"math"
+)
+
+const confidenceKey = "confidence"
+
+// WithSelfRatedConfidence asks the LLM to rate how likely each of its answers is to be correct,
+// and stores that rating in [EntityAttributes.Confidence].
+func WithSelfRatedConfidence() BasicProtocolOption {
+	return func(qa *basicProtocol) {
+		qa.extensions = append(qa.extensions, selfRatedConfidenceExtension{})
+	}
+}
+
+// WithLogprobConfidence derives [EntityAttributes.Confidence] from the log probabilities of the tokens of each answer,
+// as the probability the model gave to generating that answer. This only takes effect when the client is a [LogprobClient]
+// (such as one created by [NewOpenAILogprobClient]), and overrides any self-rated confidence.
+func WithLogprobConfidence() BasicProtocolOption {
+	return func(qa *basicProtocol) {
+		qa.logprobConfidence = true
+	}
+}
+
+type selfRatedConfidenceExtension struct{}
+
+func (selfRatedConfidenceExtension) schemaProperties() map[string]any {
+	return map[string]any{
+		confidenceKey: map[string]any{"type": "number"},
+	}
+}
+
+func (selfRatedConfidenceExtension) instructions() []string {
+	return []string{
+		fmt.Sprintf("Every answer object must include `%s`, the probability (between 0 and 1) that the answer is correct", confidenceKey),
+		"Be calibrated: of all the answers you give a confidence of 0.8, about 80% should be correct",
+		"Use a low confidence when the document is ambiguous, or when you had to infer the answer rather than read it",
+	}
+}
+
+func (selfRatedConfidenceExtension) apply(entity Entity, answer map[string]any, documentText string) error {
+	confidence, ok := answer[confidenceKey].(float64)
+	if !ok {
+		return nil
+	}
+	confidence = min(max(confidence, 0), 1)
+	entity.Attr().Confidence = &confidence
+	return nil
+}
+
+// WantsLogprobs implements [LogprobProtocol].
+func (qa *basicProtocol) WantsLogprobs() bool {
+	return qa.logprobConfidence
+}
+
+// ParseLogprobResponse implements [LogprobProtocol].
+func (qa *basicProtocol) ParseLogprobResponse(resp string, documentText string, logprobs []TokenLogprob) (map[string][]Entity, error) {
+	answers, err := qa.ParseDocumentResponse(resp, documentText)
+	if err != nil || !qa.logprobConfidence || len(logprobs) == 0 {
+		return answers, err
+	}
+
+	// Find where each token sits in the response.
+	tokenSpans := make([][2]int, len(logprobs))
+	offset := 0
+	for i, lp := range logprobs {
+		tokenSpans[i] = [2]int{offset, offset + lp.byteLen()}
+		offset += lp.byteLen()
+	}
+	ignoredKeys := make(map[string]bool)
+	for _, ext := range qa.extensions {
+		for k := range ext.schemaProperties() {
+			ignoredKeys[k] = true
+		}
+	}
+
+	// The answer objects for each question appear in the same order as the parsed entities.
+	var scanner jsonStreamScanner
+	objects, err := scanner.Feed(resp)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]int)
+	for _, obj := range objects {
+		i := seen[obj.key]
+		seen[obj.key]++
+		if i >= len(answers[obj.key]) {
+			continue
+		}
+		valueSpans, err := jsonObjectValueSpans(obj.raw)
+		if err != nil {
+			return nil, err
+		}
+		// A token may span several values, but its probability only counts once.
+		inAnswer := make([]bool, len(tokenSpans))
+		for key, span := range valueSpans {
+			if ignoredKeys[key] {
+				continue
+			}
+			start, end := obj.start+span[0], obj.start+span[1]
+			for t, ts := range tokenSpans {
+				if ts[0] < end && ts[1] > start {
+					inAnswer[t] = true
+				}
+			}
+		}
+		totalLogprob := 0.0
+		for t, ok := range inAnswer {
+			if ok {
+				totalLogprob += logprobs[t].Logprob
+			}
+		}
+		confidence := math.Exp(totalLogprob)
+		answers[obj.key][i].Attr().Confidence = &confidence
+	}
+	return answers, nil
+}
@@ -0,0 +1,137 @@
+package docqa
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// testToken is a piece of a response and the log probability of generating it.
+type testToken struct {
+	text    string
+	logprob float64
+}
+
+// splitTokens builds the response and token logprobs from its pieces.
+func splitTokens(tokens ...testToken) (string, []TokenLogprob) {
+	var sb strings.Builder
+	logprobs := make([]TokenLogprob, len(tokens))
+	for i, tok := range tokens {
+		sb.WriteString(tok.text)
+		logprobs[i] = TokenLogprob{Token: tok.text, Logprob: tok.logprob}
+	}
+	return sb.String(), logprobs
+}
+
+func TestParseLogprobResponse(t *testing.T) {
+	resp, logprobs := splitTokens(
+		testToken{`{"title": [{"answer_type": "text", "text": `, 0},
+		testToken{`"Acme"`, -0.1},
+		testToken{`}, {"answer_type": `, 0},
+		testToken{`"text"`, -0.2},
+		testToken{`, "text": "Bob"`, -0.5},
+		testToken{`}], "date": [{"answer_type": "text", "text": "May"}]}`, -0.3},
+	)
+	qa := NewBasicProtocol(GetDefaultRoleAndTask(), map[string]Type{"text": testTextType{}}, WithLogprobConfidence()).(LogprobProtocol)
+	answers, err := qa.ParseLogprobResponse(resp, "", logprobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]float64{
+		"title": {math.Exp(-0.1), math.Exp(-0.2 - 0.5)},
+		"date":  {math.Exp(-0.3)},
+	}
+	assertConfidences(t, answers, expected)
+
+	t.Run("logprob confidence not enabled", func(t *testing.T) {
+		answers, err := newTestTextProtocol().(LogprobProtocol).ParseLogprobResponse(resp, "", logprobs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range answers["title"] {
+			if e.Attr().Confidence != nil {
+				t.Errorf("expected no confidence, got %v", *e.Attr().Confidence)
+			}
+		}
+	})
+}
+
+func TestParseLogprobResponseSplitMultiByteToken(t *testing.T) {
+	// The é in Café is split across two tokens, whose text is each a replacement character (3 bytes)
+	// but which only cover one byte of the response each.
+	resp, logprobs := splitTokens(
+		testToken{`{"title": [{"answer_type": "text", "text": "Caf`, 0},
+		testToken{"�", -0.2},
+		testToken{"�", -0.3},
+		testToken{`"}, {"answer_type": "text", `, 0},
+		testToken{`"text":`, -5},
+		testToken{` "Bob"`, -1},
+		testToken{`}]}`, 0},
+	)
+	logprobs[1].Bytes = []byte{0xC3}
+	logprobs[2].Bytes = []byte{0xA9}
+	resp = strings.Replace(resp, "��", "é", 1)
+
+	qa := NewBasicProtocol(GetDefaultRoleAndTask(), map[string]Type{"text": testTextType{}}, WithLogprobConfidence()).(LogprobProtocol)
+	answers, err := qa.ParseLogprobResponse(resp, "", logprobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := answers["title"][0].(*testTextEntity).Text; got != "Café" {
+		t.Fatalf("expected Café, got %q", got)
+	}
+	// If the tokens were measured by their text, every later token would be 4 bytes out,
+	// and the key token before Bob would be counted as part of the answer.
+	assertConfidences(t, answers, map[string][]float64{
+		"title": {math.Exp(-0.2 - 0.3), math.Exp(-1)},
+	})
+}
+
+func TestParseLogprobResponseIgnoresExtensionKeys(t *testing.T) {
+	resp, logprobs := splitTokens(
+		testToken{`{"title": [{"answer_type": "text", "text": `, 0},
+		testToken{`"Acme"`, -0.1},
+		testToken{`, "confidence": `, 0},
+		testToken{`0.9`, -3},
+		testToken{`, "evidence_quotes": `, 0},
+		testToken{`["Acme Ltd"]`, -4},
+		testToken{`}]}`, 0},
+	)
+	qa := NewBasicProtocol(GetDefaultRoleAndTask(), map[string]Type{"text": testTextType{}},
+		WithSelfRatedConfidence(), WithEvidenceQuotes(), WithLogprobConfidence()).(LogprobProtocol)
+	answers, err := qa.ParseLogprobResponse(resp, "Made by Acme Ltd.", logprobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The logprob confidence replaces the self-rated one, and only covers the answer itself.
+	assertConfidences(t, answers, map[string][]float64{"title": {math.Exp(-0.1)}})
+	if ranges := answers["title"][0].Attr().EvidenceRanges; len(ranges) != 1 || ranges[0] != (Range{8, 16}) {
+		t.Errorf("expected the evidence to still be resolved, got %v", ranges)
+	}
+
+	t.Run("without logprobs the self-rated confidence is kept", func(t *testing.T) {
+		answers, err := qa.ParseLogprobResponse(resp, "Made by Acme Ltd.", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertConfidences(t, answers, map[string][]float64{"title": {0.9}})
+	})
+}
+
+func assertConfidences(t *testing.T, answers map[string][]Entity, expected map[string][]float64) {
+	t.Helper()
+	for key, confidences := range expected {
+		if len(answers[key]) != len(confidences) {
+			t.Errorf("expected %d answers to %s, got %d", len(confidences), key, len(answers[key]))
+			continue
+		}
+		for i, want := range confidences {
+			got := answers[key][i].Attr().Confidence
+			if got == nil {
+				t.Errorf("answer %d to %s: expected confidence %v, got none", i, key, want)
+			} else if math.Abs(*got-want) > 1e-9 {
+				t.Errorf("answer %d to %s: expected confidence %v, got %v", i, key, want, *got)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package docqa
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is the price of using a model, in currency units per million tokens.
+type ModelPrice struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input"`
+	Output      float64 `json:"output"`
+}
+
+// PriceTable holds the prices of a set of models, all in the same currency.
+type PriceTable struct {
+	Currency string                `json:"currency"`
+	Models   map[string]ModelPrice `json:"models"`
+}
+
+// CostReport is the cost of a batch of calls.
+type CostReport struct {
+	Currency string
+	Total    float64
+	PerCall  []float64
+}
+
+// Price finds the price of the model. If there is no exact match, the longest model name
+// that is a prefix of the model is used, so `gpt-4o` also prices `gpt-4o-2024-08-06`.
+func (pt PriceTable) Price(model string) (ModelPrice, error) {
+	if price, ok := pt.Models[model]; ok {
+		return price, nil
+	}
+	bestKey := ""
+	for _, key := range sortedKeys(pt.Models) {
+		if strings.HasPrefix(model, key) && len(key) > len(bestKey) {
+			bestKey = key
+		}
+	}
+	if bestKey == "" {
+		return ModelPrice{}, fmt.Errorf("no price for model %s", model)
+	}
+	return pt.Models[bestKey], nil
+}
+
+// Cost converts the usage of a call to the given model into its cost.
+// Cached input tokens are charged at the cached input price, and the rest of the input at the input price.
+// Tokens written to the provider's prompt cache (such as Anthropic's `cache_creation_input_tokens`) are not counted separately,
+// so they are charged at the input price, which underestimates providers that charge extra for cache writes.
+// Reasoning tokens are charged as output tokens, as they are included in [LLMUsage.OutputTokens].
+func (pt PriceTable) Cost(model string, usage LLMUsage) (float64, error) {
+	price, err := pt.Price(model)
+	if err != nil {
+		return 0, err
+	}
+	uncached := usage.InputTokens - usage.CachedInputTokens
+	cost := float64(uncached)*price.Input +
+		float64(usage.CachedInputTokens)*price.CachedInput +
+		float64(usage.OutputTokens)*price.Output
+	return cost / 1_000_000, nil
+}
+
+// BatchCost calculates the cost of each of a batch of calls to the given model, and their total.
+func (pt PriceTable) BatchCost(model string, usages []LLMUsage) (CostReport, error) {
+	report := CostReport{
+		Currency: pt.Currency,
+		PerCall:  make([]float64, len(usages)),
+	}
+	for i, usage := range usages {
+		cost, err := pt.Cost(model, usage)
+		if err != nil {
+			return CostReport{}, err
+		}
+		report.PerCall[i] = cost
+		report.Total += cost
+	}
+	return report, nil
+}
@@ -0,0 +1,92 @@
+package docqa
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceTable(t *testing.T) {
+	table := PriceTable{
+		Currency: "USD",
+		Models: map[string]ModelPrice{
+			"gpt-4o":      {Input: 2.5, CachedInput: 1.25, Output: 10},
+			"gpt-4o-mini": {Input: 0.15, CachedInput: 0.075, Output: 0.6},
+			"claude":      {Input: 3, CachedInput: 0.3, Output: 15},
+		},
+	}
+
+	priceCases := []struct {
+		model string
+		want  ModelPrice
+		ok    bool
+	}{
+		{model: "gpt-4o", want: table.Models["gpt-4o"], ok: true},
+		{model: "gpt-4o-2024-08-06", want: table.Models["gpt-4o"], ok: true},
+		{model: "gpt-4o-mini", want: table.Models["gpt-4o-mini"], ok: true},
+		{model: "gpt-4o-mini-2024-07-18", want: table.Models["gpt-4o-mini"], ok: true},
+		{model: "claude-sonnet-4", want: table.Models["claude"], ok: true},
+		{model: "gpt-4", ok: false},
+		{model: "", ok: false},
+	}
+	for _, tc := range priceCases {
+		price, err := table.Price(tc.model)
+		if (err == nil) != tc.ok {
+			t.Errorf("%q: expected ok to be %v, got error %v", tc.model, tc.ok, err)
+			continue
+		}
+		if price != tc.want {
+			t.Errorf("%q: expected %+v, got %+v", tc.model, tc.want, price)
+		}
+	}
+
+	costCases := []struct {
+		name  string
+		model string
+		usage LLMUsage
+		want  float64
+	}{
+		{name: "input and output", model: "gpt-4o", usage: LLMUsage{InputTokens: 1_000_000, OutputTokens: 100_000}, want: 2.5 + 1},
+		{name: "cached input", model: "gpt-4o", usage: LLMUsage{InputTokens: 1_000_000, CachedInputTokens: 400_000, OutputTokens: 0}, want: 0.6*2.5 + 0.4*1.25},
+		{name: "reasoning is output", model: "gpt-4o", usage: LLMUsage{OutputTokens: 1_000_000, ReasoningTokens: 900_000}, want: 10},
+		{name: "prefix", model: "gpt-4o-mini-2024-07-18", usage: LLMUsage{InputTokens: 2_000_000, CachedInputTokens: 2_000_000}, want: 0.15},
+		{name: "no usage", model: "claude", usage: LLMUsage{}, want: 0},
+	}
+	for _, tc := range costCases {
+		cost, err := table.Cost(tc.model, tc.usage)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if math.Abs(cost-tc.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, cost)
+		}
+	}
+	if _, err := table.Cost("gpt-4", LLMUsage{InputTokens: 1}); err == nil {
+		t.Errorf("expected an unknown model to have no cost")
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		usages := []LLMUsage{
+			{InputTokens: 1_000_000},
+			{InputTokens: 1_000_000, CachedInputTokens: 1_000_000, OutputTokens: 1_000_000},
+		}
+		report, err := table.BatchCost("claude-sonnet-4", usages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Currency != "USD" || len(report.PerCall) != 2 {
+			t.Fatalf("unexpected report %+v", report)
+		}
+		for i, want := range []float64{3, 0.3 + 15} {
+			if math.Abs(report.PerCall[i]-want) > 1e-9 {
+				t.Errorf("call %d: expected %v, got %v", i, want, report.PerCall[i])
+			}
+		}
+		if math.Abs(report.Total-18.3) > 1e-9 {
+			t.Errorf("expected a total of 18.3, got %v", report.Total)
+		}
+		if _, err := table.BatchCost("gpt-4", usages); err == nil {
+			t.Errorf("expected an unknown model to fail")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package docqa
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ExtractAnswersEnsemble answers the questions once with each of the clients, in parallel, and then votes on the answers.
+// The same client may be given multiple times to take multiple samples, and different clients may use different models or temperatures.
+//
+// Entities are aligned across samples by their type and content. An entity survives if the fraction of samples
+// that produced it is at least minAgreement, and that fraction is stored in its [EntityAttributes.Agreement].
+// Surviving entities are ordered by agreement, most agreed first.
+// If more entities survive than a question's [Question.MaxAnswers], only the most agreed are kept,
+// and if fewer survive than its [Question.MinAnswers], an error is returned.
+// The returned usage is the sum of the usage of every sample.
+func ExtractAnswersEnsemble(ctx context.Context, clients []Client, qa Protocol, questions map[string]Question, documentText string, minAgreement float64, opts ...ExtractOption) (map[string][]Entity, LLMUsage, error) {
+	if len(clients) == 0 {
+		return nil, LLMUsage{}, fmt.Errorf("ensemble needs at least one client")
+	}
+	if err := validateQuestions(questions); err != nil {
+		return nil, LLMUsage{}, err
+	}
+	samples := make([]map[string][]Entity, len(clients))
+	usages := make([]LLMUsage, len(clients))
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples[i], usages[i], errs[i] = ExtractAnswersContext(ctx, client, qa, questions, documentText, opts...)
+		}()
+	}
+	wg.Wait()
+	usage := SumUsage(usages...)
+	for i, err := range errs {
+		if err != nil {
+			return nil, usage, fmt.Errorf("ensemble sample %d failed: %w", i, err)
+		}
+	}
+	answers, err := voteOnAnswers(samples, minAgreement)
+	if err != nil {
+		return nil, usage, err
+	}
+	// Each sample had the right number of answers, but different samples may have disagreed on which they were.
+	trimToMaxAnswers(questions, answers)
+	if err := ValidateCardinality(questions, answers); err != nil {
+		return nil, usage, fmt.Errorf("ensemble did not agree on enough answers: %w", err)
+	}
+	return answers, usage, nil
+}
+
+// voteOnAnswers aligns the entities of each sample by identity, and keeps those that enough samples agree on.
+func voteOnAnswers(samples []map[string][]Entity, minAgreement float64) (map[string][]Entity, error) {
+	type candidate struct {
+		entity Entity
+		votes  int
+		order  int
+	}
+	candidates := make(map[string]map[string]*candidate)
+	for _, sample := range samples {
+		for qKey, entities := range sample {
+			if _, ok := candidates[qKey]; !ok {
+				candidates[qKey] = make(map[string]*candidate)
+			}
+			seen := make(map[string]bool)
+			for _, e := range entities {
+				id, err := entityIdentity(e)
+				if err != nil {
+					return nil, err
+				}
+				// Each sample only gets one vote per entity, even if it repeats itself.
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				if c, ok := candidates[qKey][id]; ok {
+					c.votes++
+				} else {
+					candidates[qKey][id] = &candidate{entity: e, votes: 1, order: len(candidates[qKey])}
+				}
+			}
+		}
+	}
+
+	answers := make(map[string][]Entity)
+	for qKey, qCandidates := range candidates {
+		survivors := make([]*candidate, 0)
+		for _, c := range qCandidates {
+			if float64(c.votes)/float64(len(samples)) >= minAgreement {
+				survivors = append(survivors, c)
+			}
+		}
+		slices.SortFunc(survivors, func(a, b *candidate) int {
+			if a.votes != b.votes {
+				return b.votes - a.votes
+			}
+			return a.order - b.order
+		})
+		answers[qKey] = make([]Entity, len(survivors))
+		for i, c := range survivors {
+			c.entity.Attr().Agreement = float64(c.votes) / float64(len(samples))
+			answers[qKey][i] = c.entity
+		}
+	}
+	return answers, nil
+}
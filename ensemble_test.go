@@ -0,0 +1,183 @@
+package docqa
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// textSample builds an ensemble sample answering the question "q" with text entities.
+func textSample(texts ...string) map[string][]Entity {
+	entities := make([]Entity, len(texts))
+	for i, text := range texts {
+		entities[i] = &testTextEntity{Text: text}
+	}
+	return map[string][]Entity{"q": entities}
+}
+
+// textResponse builds a basic protocol response answering the question "q" with text answers.
+func textResponse(texts ...string) string {
+	answers := make([]string, len(texts))
+	for i, text := range texts {
+		answers[i] = fmt.Sprintf(`{"answer_type":"text","text":%q}`, text)
+	}
+	return fmt.Sprintf(`{"q":[%s]}`, strings.Join(answers, ","))
+}
+
+func TestVoteOnAnswers(t *testing.T) {
+	cases := []struct {
+		name         string
+		samples      []map[string][]Entity
+		minAgreement float64
+		want         []string
+		agreements   []float64
+	}{
+		{
+			name:         "unanimous",
+			samples:      []map[string][]Entity{textSample("A"), textSample("A")},
+			minAgreement: 1,
+			want:         []string{"A"},
+			agreements:   []float64{1},
+		},
+		{
+			name:         "threshold is inclusive",
+			samples:      []map[string][]Entity{textSample("A", "B"), textSample("A"), textSample("A"), textSample("B")},
+			minAgreement: 0.5,
+			want:         []string{"A", "B"},
+			agreements:   []float64{0.75, 0.5},
+		},
+		{
+			name:         "below threshold is dropped",
+			samples:      []map[string][]Entity{textSample("A", "B"), textSample("A"), textSample("A")},
+			minAgreement: 0.5,
+			want:         []string{"A"},
+			agreements:   []float64{1},
+		},
+		{
+			name:         "one vote per sample",
+			samples:      []map[string][]Entity{textSample("B", "B", "B"), textSample("A")},
+			minAgreement: 0.5,
+			want:         []string{"B", "A"},
+			agreements:   []float64{0.5, 0.5},
+		},
+		{
+			name:         "ordered by votes",
+			samples:      []map[string][]Entity{textSample("C", "B", "A"), textSample("A", "B"), textSample("A")},
+			minAgreement: 0,
+			want:         []string{"A", "B", "C"},
+			agreements:   []float64{1, 2.0 / 3, 1.0 / 3},
+		},
+		{
+			name:         "ties keep first appearance",
+			samples:      []map[string][]Entity{textSample("B", "A"), textSample("C", "A")},
+			minAgreement: 0,
+			want:         []string{"A", "B", "C"},
+			agreements:   []float64{1, 0.5, 0.5},
+		},
+		{
+			name:         "no agreement",
+			samples:      []map[string][]Entity{textSample("A"), textSample("B")},
+			minAgreement: 1,
+			want:         []string{},
+			agreements:   []float64{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			answers, err := voteOnAnswers(tc.samples, tc.minAgreement)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := answers["q"]
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %d answers, got %d", len(tc.want), len(got))
+			}
+			for i, e := range got {
+				if text := e.(*testTextEntity).Text; text != tc.want[i] {
+					t.Errorf("answer %d: expected %q, got %q", i, tc.want[i], text)
+				}
+				if e.Attr().Agreement != tc.agreements[i] {
+					t.Errorf("answer %d: expected agreement %v, got %v", i, tc.agreements[i], e.Attr().Agreement)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractAnswersEnsemble(t *testing.T) {
+	qa := newTestTextProtocol()
+	usage := LLMUsage{InputTokens: 10, OutputTokens: 1}
+	cases := []struct {
+		name         string
+		question     Question
+		responses    []string
+		minAgreement float64
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:         "votes",
+			question:     Question{Question: "Q?", AllowedTypeKeys: []string{"text"}},
+			responses:    []string{textResponse("A", "B"), textResponse("A"), textResponse("A", "C")},
+			minAgreement: 0.5,
+			want:         []string{"A"},
+		},
+		{
+			name:         "trimmed to the most agreed",
+			question:     Question{Question: "Q?", AllowedTypeKeys: []string{"text"}, MaxAnswers: 1},
+			responses:    []string{textResponse("A"), textResponse("B"), textResponse("B")},
+			minAgreement: 0,
+			want:         []string{"B"},
+		},
+		{
+			name:         "too few agreed answers",
+			question:     Question{Question: "Q?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1},
+			responses:    []string{textResponse("A"), textResponse("B")},
+			minAgreement: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "failed sample",
+			question:     Question{Question: "Q?", AllowedTypeKeys: []string{"text"}},
+			responses:    []string{textResponse("A"), "not json"},
+			minAgreement: 0,
+			wantErr:      true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clients := make([]Client, len(tc.responses))
+			for i, resp := range tc.responses {
+				clients[i] = stubResponses(usage, resp)
+			}
+			questions := map[string]Question{"q": tc.question}
+			answers, gotUsage, err := ExtractAnswersEnsemble(context.Background(), clients, qa, questions, "doc", tc.minAgreement)
+			wantUsage := LLMUsage{InputTokens: 10 * len(clients), OutputTokens: len(clients)}
+			if gotUsage != wantUsage {
+				t.Errorf("expected usage %+v, got %+v", wantUsage, gotUsage)
+			}
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", answers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(answers["q"]) != len(tc.want) {
+				t.Fatalf("expected %v, got %d answers", tc.want, len(answers["q"]))
+			}
+			for i, e := range answers["q"] {
+				if text := e.(*testTextEntity).Text; text != tc.want[i] {
+					t.Errorf("answer %d: expected %q, got %q", i, tc.want[i], text)
+				}
+			}
+		})
+	}
+
+	if _, _, err := ExtractAnswersEnsemble(context.Background(), nil, qa, map[string]Question{}, "doc", 0); err == nil {
+		t.Errorf("expected an ensemble without clients to fail")
+	}
+}
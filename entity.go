@@ -1,6 +1,12 @@
 package docqa
 
-// Range defines a range of characters from the source document.
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Range defines a range of the source document, as byte offsets into its text, so that `documentText[r.Start:r.End]` is the text it covers.
 type Range struct {
 	Start int `json:"start"`
 	End   int `json:"end"`
@@ -25,6 +31,13 @@ func IndefRange() Range {
 type EntityAttributes struct {
 	EvidenceRanges []Range `json:"evidence_positions"`
 	LocalisedRange Range   `json:"localised_range"`
+	// UnresolvedEvidence lists supporting quotes given by the LLM that could not be found in the document.
+	UnresolvedEvidence []string `json:"unresolved_evidence,omitempty"`
+	// Confidence is an estimate, between 0 and 1, of how likely this entity is to be correct, or nil if unknown.
+	Confidence *float64 `json:"confidence,omitempty"`
+	// Agreement is the fraction of samples that produced this entity when using [ExtractAnswersEnsemble],
+	// or zero otherwise.
+	Agreement float64 `json:"agreement,omitempty"`
 }
 
 // Attr gets the [EntityAttributes] for this [Entity].
@@ -41,3 +54,21 @@ type Entity interface {
 	// Attr returns the [EntityAttributes]..
 	Attr() *EntityAttributes
 }
+
+// entityIdentity builds a string that is equal for two entities if they have the same type and content,
+// regardless of their attributes. The type of a [KeyedEntity] includes its key, as several keys may share a Go type.
+func entityIdentity(e Entity) (string, error) {
+	content, err := e.MakeContent()
+	if err != nil {
+		return "", err
+	}
+	bs, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	typeName := reflect.TypeOf(e).String()
+	if ke, ok := e.(KeyedEntity); ok {
+		typeName += "/" + ke.EntityKey()
+	}
+	return fmt.Sprintf("%s:%s", typeName, bs), nil
+}
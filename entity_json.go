@@ -31,9 +31,21 @@ func (e *EntityJsoner) AddFactories(factories map[string]func() Entity) {
 	}
 }
 
+// KeyedEntity is an [Entity] that knows which key it was registered with in an [EntityJsoner].
+// This is needed when several keys create entities of the same Go type, such as composite entities.
+type KeyedEntity interface {
+	Entity
+	// EntityKey returns the key of the factory that creates this kind of entity.
+	EntityKey() string
+}
+
 // Encode converts an [Entity] into a json-serialisable object.
 func (enc *EntityJsoner) Encode(e Entity) (any, error) {
 	key, ok := enc.entityTypeLookup[reflect.TypeOf(e)]
+	if ke, isKeyed := e.(KeyedEntity); isKeyed {
+		key = ke.EntityKey()
+		_, ok = enc.entityFactories[key]
+	}
 	if !ok {
 		return nil, fmt.Errorf("unrecognised entity type %T", e)
 	}
@@ -0,0 +1,89 @@
+package docqa
+
+import (
+	"testing"
+)
+
+// testKeyedEntity is a testTextEntity that, like a composite entity, may be created under several keys.
+type testKeyedEntity struct {
+	testTextEntity
+	key string
+}
+
+func (e *testKeyedEntity) EntityKey() string {
+	return e.key
+}
+
+func TestEntityIdentity(t *testing.T) {
+	confidence := 0.9
+	cases := []struct {
+		name string
+		a, b Entity
+		same bool
+	}{
+		{
+			name: "same content",
+			a:    &testTextEntity{Text: "Acme"},
+			b:    &testTextEntity{Text: "Acme"},
+			same: true,
+		},
+		{
+			name: "attributes are ignored",
+			a:    &testTextEntity{Text: "Acme", EntityAttributes: EntityAttributes{EvidenceRanges: []Range{{0, 4}}}},
+			b:    &testTextEntity{Text: "Acme", EntityAttributes: EntityAttributes{Confidence: &confidence, Agreement: 0.5}},
+			same: true,
+		},
+		{
+			name: "different content",
+			a:    &testTextEntity{Text: "Acme"},
+			b:    &testTextEntity{Text: "Bob"},
+		},
+		{
+			name: "different go types",
+			a:    &testTextEntity{Text: "Acme"},
+			b:    &testKeyedEntity{testTextEntity: testTextEntity{Text: "Acme"}, key: "text"},
+		},
+		{
+			name: "same key",
+			a:    &testKeyedEntity{testTextEntity: testTextEntity{Text: "Acme"}, key: "buyer"},
+			b:    &testKeyedEntity{testTextEntity: testTextEntity{Text: "Acme"}, key: "buyer"},
+			same: true,
+		},
+		{
+			name: "different keys",
+			a:    &testKeyedEntity{testTextEntity: testTextEntity{Text: "Acme"}, key: "buyer"},
+			b:    &testKeyedEntity{testTextEntity: testTextEntity{Text: "Acme"}, key: "seller"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := entityIdentity(tc.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := entityIdentity(tc.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (a == b) != tc.same {
+				t.Errorf("expected identities to be equal: %v, got %q and %q", tc.same, a, b)
+			}
+		})
+	}
+
+	t.Run("keyed entities are not merged across chunks", func(t *testing.T) {
+		questions := map[string]Question{"parties": {}}
+		buyer := &testKeyedEntity{testTextEntity: testTextEntity{Text: "Acme"}, key: "buyer"}
+		seller := &testKeyedEntity{testTextEntity: testTextEntity{Text: "Acme"}, key: "seller"}
+		answers, err := mergeChunkAnswers(questions, []map[string][]Entity{
+			{"parties": {buyer}},
+			{"parties": {seller}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(answers["parties"]) != 2 {
+			t.Errorf("expected the buyer and seller to both be kept, got %d entities", len(answers["parties"]))
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// HTTPStatusError is returned by HTTP-backed clients when the API responds with a non-2xx status code.
+type HTTPStatusError struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// Error implements error.
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("api responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the status code indicates a transient failure,
+// such as rate limiting (429) or a server error (5xx).
+func (e *HTTPStatusError) Retryable() bool {
+	switch {
+	case e.StatusCode == http.StatusRequestTimeout,
+		e.StatusCode == http.StatusConflict,
+		e.StatusCode == http.StatusTooManyRequests,
+		e.StatusCode >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether an error returned by a [Client] is likely to be transient.
+// Context cancellation is never retryable, HTTP status errors are classified by status code,
+// and only network failures that may not happen again (timeouts of the [http.Client] or connection, refused or reset connections,
+// and connections closed mid-response) are retryable. Other transport errors, such as an unsupported
+// URL scheme or an invalid TLS certificate, are configuration problems that retrying cannot fix.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// The caller's own deadline is returned as is, but a timeout set on the http.Client wraps it, and is worth retrying.
+		var urlErr *url.Error
+		return errors.As(err, &urlErr) && urlErr.Err != context.DeadlineExceeded && urlErr.Timeout()
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Retryable()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, transient := range []error{syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED, syscall.EPIPE, io.ErrUnexpectedEOF} {
+		if errors.Is(err, transient) {
+			return true
+		}
+	}
+	// The server closing the connection before responding is reported as a plain EOF.
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && errors.Is(urlErr.Err, io.EOF)
+}
+
+// PartialResponse holds whatever an LLM produced before a call failed, so callers can decide how to recover.
+type PartialResponse struct {
+	// Content is the (possibly empty or incomplete) response text.
+	Content string
+	// Usage is the usage of the failed call, which is usually still billed.
+	Usage LLMUsage
+}
+
+// TruncatedError is returned when the LLM stopped because it reached its output token limit,
+// so the content is incomplete. Retrying the same request is unlikely to help without raising the limit or asking for less.
+type TruncatedError struct {
+	PartialResponse
+}
+
+// Error implements error.
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("response was truncated by the output token limit after %d output tokens", e.Usage.OutputTokens)
+}
+
+// ContentFilterError is returned when the provider's content filter blocked the prompt or the response.
+type ContentFilterError struct {
+	PartialResponse
+	// Reason is the provider's reason for blocking, if given.
+	Reason string
+}
+
+// Error implements error.
+func (e *ContentFilterError) Error() string {
+	if e.Reason == "" {
+		return "response was blocked by the content filter"
+	}
+	return fmt.Sprintf("response was blocked by the content filter: %s", e.Reason)
+}
+
+// RefusalError is returned when the model refused to answer.
+type RefusalError struct {
+	PartialResponse
+	// Refusal is the model's explanation of why it refused.
+	Refusal string
+}
+
+// Error implements error.
+func (e *RefusalError) Error() string {
+	return fmt.Sprintf("model refused to answer: %s", e.Refusal)
+}
+
+// MalformedResponseError is returned when a response could not be parsed,
+// either because the API response was not understood or because the content was not valid for the [Protocol].
+type MalformedResponseError struct {
+	PartialResponse
+	Err error
+}
+
+// Error implements error.
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("failed to parse response: %v: %s", e.Err, truncateForError(e.Content))
+}
+
+// Unwrap returns the underlying parse error.
+func (e *MalformedResponseError) Unwrap() error {
+	return e.Err
+}
+
+func newMalformedResponseError(content string, usage LLMUsage, err error) *MalformedResponseError {
+	return &MalformedResponseError{
+		PartialResponse: PartialResponse{Content: content, Usage: usage},
+		Err:             err,
+	}
+}
@@ -0,0 +1,201 @@
+package docqa
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	evidenceKey = "evidence_quotes"
+	// evidenceMaxEditFraction is the fraction of a quote's characters that may differ from the document for a fuzzy match.
+	evidenceMaxEditFraction = 0.1
+	// evidenceMaxFuzzyWork caps the cost (quote length times document length) of fuzzy matching a single quote.
+	evidenceMaxFuzzyWork = 200_000_000
+)
+
+// WithEvidenceQuotes asks the LLM for verbatim quotes from the document that support each answer,
+// and resolves them to [EntityAttributes.EvidenceRanges]. Quotes are matched exactly where possible,
+// then ignoring differences in whitespace, case and punctuation style, and finally allowing a few differing characters.
+// Quotes that cannot be found are listed in [EntityAttributes.UnresolvedEvidence].
+func WithEvidenceQuotes() BasicProtocolOption {
+	return func(qa *basicProtocol) {
+		qa.extensions = append(qa.extensions, evidenceExtension{})
+	}
+}
+
+// NewEvidenceProtocol creates a protocol like [NewBasicProtocol], which also cites evidence for each answer (see [WithEvidenceQuotes]).
+func NewEvidenceProtocol(roleTask RoleAndTask, types map[string]Type) Protocol {
+	return NewBasicProtocol(roleTask, types, WithEvidenceQuotes())
+}
+
+type evidenceExtension struct{}
+
+func (evidenceExtension) schemaProperties() map[string]any {
+	return map[string]any{
+		evidenceKey: map[string]any{
+			"type":  "array",
+			"items": map[string]any{"type": "string"},
+		},
+	}
+}
+
+func (evidenceExtension) instructions() []string {
+	return []string{
+		fmt.Sprintf("Every answer object must include `%s`, a list of quotes from the document that support the answer", evidenceKey),
+		"Each quote must be copied verbatim from the document, character for character, and should be a short sentence or phrase",
+		"Do not paraphrase, summarise, or join separate parts of the document into a single quote",
+	}
+}
+
+func (evidenceExtension) apply(entity Entity, answer map[string]any, documentText string) error {
+	quotesAny, ok := answer[evidenceKey]
+	if !ok {
+		return nil
+	}
+	quotes, ok := quotesAny.([]any)
+	if !ok {
+		return fmt.Errorf("%s was not a list", evidenceKey)
+	}
+	attr := entity.Attr()
+	for _, q := range quotes {
+		quote, ok := q.(string)
+		if !ok {
+			return fmt.Errorf("%s contained a non-string quote", evidenceKey)
+		}
+		if r, ok := FindQuote(documentText, quote); ok {
+			attr.EvidenceRanges = append(attr.EvidenceRanges, r)
+		} else {
+			attr.UnresolvedEvidence = append(attr.UnresolvedEvidence, quote)
+		}
+	}
+	return nil
+}
+
+// FindQuote finds the [Range] (in byte offsets) of the quote within the document.
+// It tries an exact match first, then a match that ignores differences in whitespace, case and quote/dash style,
+// and finally a fuzzy match that allows a small number of differing characters.
+func FindQuote(documentText, quote string) (Range, bool) {
+	quote = strings.TrimSpace(quote)
+	if quote == "" {
+		return IndefRange(), false
+	}
+	if i := strings.Index(documentText, quote); i >= 0 {
+		return Range{Start: i, End: i + len(quote)}, true
+	}
+	doc := normaliseForMatching(documentText)
+	q := normaliseForMatching(quote)
+	if len(q.runes) == 0 {
+		return IndefRange(), false
+	}
+	if i := strings.Index(string(doc.runes), string(q.runes)); i >= 0 {
+		start := utf8.RuneCountInString(string(doc.runes)[:i])
+		return doc.originalRange(start, start+len(q.runes)), true
+	}
+	if len(q.runes)*len(doc.runes) > evidenceMaxFuzzyWork {
+		return IndefRange(), false
+	}
+	maxEdits := int(float64(len(q.runes)) * evidenceMaxEditFraction)
+	if maxEdits == 0 {
+		return IndefRange(), false
+	}
+	start, end, ok := approximateSubstring(doc.runes, q.runes, maxEdits)
+	if !ok {
+		return IndefRange(), false
+	}
+	return doc.originalRange(start, end), true
+}
+
+// normalisedText is text that has been simplified for matching, remembering where each rune came from.
+type normalisedText struct {
+	runes []rune
+	// starts and ends hold the byte offsets in the original text that each normalised rune spans.
+	starts []int
+	ends   []int
+}
+
+func (n normalisedText) originalRange(start, end int) Range {
+	return Range{Start: n.starts[start], End: n.ends[end-1]}
+}
+
+// normaliseForMatching lower cases the text, collapses whitespace runs into single spaces,
+// and replaces typographic quotes and dashes with their plain equivalents.
+func normaliseForMatching(text string) normalisedText {
+	var n normalisedText
+	lastWasSpace := true
+	for i, r := range text {
+		end := i + utf8.RuneLen(r)
+		if unicode.IsSpace(r) {
+			if lastWasSpace {
+				if len(n.ends) > 0 && n.runes[len(n.runes)-1] == ' ' {
+					n.ends[len(n.ends)-1] = end
+				}
+				continue
+			}
+			lastWasSpace = true
+			r = ' '
+		} else {
+			lastWasSpace = false
+			switch r {
+			case '‘', '’', '‚', '′':
+				r = '\''
+			case '“', '”', '„', '″':
+				r = '"'
+			case '‐', '‑', '‒', '–', '—', '−':
+				r = '-'
+			default:
+				r = unicode.ToLower(r)
+			}
+		}
+		n.runes = append(n.runes, r)
+		n.starts = append(n.starts, i)
+		n.ends = append(n.ends, end)
+	}
+	// Drop a trailing space, so the text is trimmed at both ends.
+	if len(n.runes) > 0 && n.runes[len(n.runes)-1] == ' ' {
+		n.runes, n.starts, n.ends = n.runes[:len(n.runes)-1], n.starts[:len(n.starts)-1], n.ends[:len(n.ends)-1]
+	}
+	return n
+}
+
+// approximateSubstring finds the substring of text with the smallest edit distance to pattern (Sellers' algorithm),
+// returning its rune offsets if that distance is at most maxEdits.
+func approximateSubstring(text, pattern []rune, maxEdits int) (int, int, bool) {
+	m := len(pattern)
+	// cost[i] is the edit distance between pattern[:i] and the best substring of text ending at the current position,
+	// and start[i] is where that substring starts.
+	cost := make([]int, m+1)
+	start := make([]int, m+1)
+	prevCost := make([]int, m+1)
+	prevStart := make([]int, m+1)
+	for i := range prevCost {
+		prevCost[i] = i
+	}
+	bestCost, bestStart, bestEnd := maxEdits+1, 0, 0
+	for j := 1; j <= len(text); j++ {
+		cost[0], start[0] = 0, j
+		for i := 1; i <= m; i++ {
+			sub := prevCost[i-1]
+			if pattern[i-1] != text[j-1] {
+				sub++
+			}
+			cost[i], start[i] = sub, prevStart[i-1]
+			if skipText := prevCost[i] + 1; skipText < cost[i] {
+				cost[i], start[i] = skipText, prevStart[i]
+			}
+			if skipPattern := cost[i-1] + 1; skipPattern < cost[i] {
+				cost[i], start[i] = skipPattern, start[i-1]
+			}
+		}
+		if cost[m] < bestCost {
+			bestCost, bestStart, bestEnd = cost[m], start[m], j
+		}
+		cost, prevCost = prevCost, cost
+		start, prevStart = prevStart, start
+	}
+	if bestCost > maxEdits || bestEnd <= bestStart {
+		return 0, 0, false
+	}
+	return bestStart, bestEnd, true
+}
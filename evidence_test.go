@@ -0,0 +1,97 @@
+package docqa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindQuote(t *testing.T) {
+	const doc = "The Agreement is made between Acme Ltd.\nand  Bob’s Café — a partnership.\nPayment is due within thirty days of invoice."
+	cases := []struct {
+		name  string
+		doc   string
+		quote string
+		// match is the text of the document that should be found, or empty if the quote should not be found.
+		match string
+	}{
+		{
+			name:  "exact",
+			doc:   doc,
+			quote: "made between Acme Ltd.",
+			match: "made between Acme Ltd.",
+		},
+		{
+			name:  "surrounding whitespace is trimmed",
+			doc:   doc,
+			quote: "  Acme Ltd.\n",
+			match: "Acme Ltd.",
+		},
+		{
+			name:  "whitespace and case differ",
+			doc:   doc,
+			quote: "acme ltd. and bob’s",
+			match: "Acme Ltd.\nand  Bob’s",
+		},
+		{
+			name:  "plain quotes and dashes match typographic ones",
+			doc:   doc,
+			quote: "Bob's Café - a partnership",
+			match: "Bob’s Café — a partnership",
+		},
+		{
+			name:  "fuzzy match with a typo",
+			doc:   doc,
+			quote: "Payment is due withn thirty days of invoice",
+			match: "Payment is due within thirty days of invoice",
+		},
+		{
+			name:  "too many differences",
+			doc:   doc,
+			quote: "Payment is owed inside forty days of billing",
+		},
+		{
+			name:  "short quotes are not fuzzy matched",
+			doc:   doc,
+			quote: "Acmf",
+		},
+		{
+			name:  "empty quote",
+			doc:   doc,
+			quote: " \n ",
+		},
+		{
+			name:  "empty document",
+			doc:   "",
+			quote: "Acme",
+		},
+		{
+			name:  "first of several exact matches",
+			doc:   "one two one two",
+			quote: "one two",
+			match: "one two",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, ok := FindQuote(tc.doc, tc.quote)
+			if tc.match == "" {
+				if ok {
+					t.Errorf("expected no match, got %q", tc.doc[r.Start:r.End])
+				}
+				if !r.IsIndef() {
+					t.Errorf("expected an indefinite range when there is no match, got %v", r)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected a match for %q", tc.quote)
+			}
+			if got := tc.doc[r.Start:r.End]; got != tc.match {
+				t.Errorf("expected to match %q, got %q", tc.match, got)
+			}
+			if want := strings.Index(tc.doc, tc.match); r.Start != want {
+				t.Errorf("expected the match to start at %d, got %d", want, r.Start)
+			}
+		})
+	}
+}
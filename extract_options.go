@@ -0,0 +1,75 @@
+package docqa
+
+import (
+	"context"
+	"time"
+)
+
+// ExtractOption configures a call to [ExtractAnswers] or one of its variants.
+type ExtractOption func(*extractConfig)
+
+type extractConfig struct {
+	observer         Observer
+	repairAttempts   int
+	chunkParallelism int
+	maxPassages      int
+}
+
+func newExtractConfig(opts []ExtractOption) *extractConfig {
+	cfg := &extractConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithObserver notifies the observer of each LLM call made while extracting answers.
+// Unlike [NewObservedClient], the reported error also includes failures to parse the response.
+func WithObserver(observer Observer) ExtractOption {
+	return func(c *extractConfig) {
+		c.observer = observer
+	}
+}
+
+// WithRepairAttempts allows up to n extra turns to correct a response that could not be parsed.
+// Each turn sends the model its previous response along with a list of what was wrong with it,
+// using a [ConversationClient] if possible. Only responses that the protocol failed to parse, or that had the wrong number
+// of answers, are repaired; errors from the client itself, such as refusals, truncation, or an empty response, are returned straight away.
+func WithRepairAttempts(n int) ExtractOption {
+	return func(c *extractConfig) {
+		c.repairAttempts = n
+	}
+}
+
+// WithChunkParallelism allows [ExtractAnswersChunked] and [ExtractAnswersRetrieved] to make up to n calls at once.
+// By default, calls are made one at a time.
+func WithChunkParallelism(n int) ExtractOption {
+	return func(c *extractConfig) {
+		c.chunkParallelism = n
+	}
+}
+
+// WithMaxPassagesPerCall allows [ExtractAnswersRetrieved] to send up to n passages in each call.
+// Questions that share a passage are answered in the same call as long as their passages fit,
+// so raising this makes fewer, larger calls. By default, it is the number of passages per question.
+func WithMaxPassagesPerCall(n int) ExtractOption {
+	return func(c *extractConfig) {
+		c.maxPassages = n
+	}
+}
+
+// observe reports a call to the observer, if there is one.
+func (c *extractConfig) observe(ctx context.Context, start time.Time, systemPrompt, userPrompt string, schema map[string]any, resp string, usage LLMUsage, err error) {
+	if c.observer == nil {
+		return
+	}
+	c.observer.ObserveCall(ctx, CallTrace{
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Schema:       schema,
+		Response:     resp,
+		Latency:      time.Since(start),
+		Usage:        usage,
+		Err:          err,
+	})
+}
@@ -0,0 +1,278 @@
+package docqa
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var gbnfPrimitiveRules = map[string]string{
+	"ws":      `[ \t\n]{0,20}`,
+	"string":  `"\"" ( [^"\\\x7F\x00-\x1F] | "\\" ( ["\\/bfnrt] | "u" [0-9a-fA-F]{4} ) )* "\"" ws`,
+	"integer": `"-"? ( [0-9] | [1-9] [0-9]{1,15} ) ws`,
+	"number":  `"-"? ( [0-9] | [1-9] [0-9]{1,15} ) ( "." [0-9]+ )? ( [eE] [-+]? [0-9]+ )? ws`,
+	"boolean": `( "true" | "false" ) ws`,
+	"null":    `"null" ws`,
+}
+
+var gbnfInvalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+// SchemaToGBNF converts a json schema, such as one from [Protocol.Schema], into a GBNF grammar
+// that constrains a llama.cpp style model to only generate matching json.
+// It supports `type` (including objects, arrays, strings, integers, numbers, booleans and null),
+// `properties`, `required`, `items`, `minItems`, `maxItems`, `enum`, `const`, `anyOf`, `oneOf`, and local `$ref`s.
+// Object properties are generated in sorted order.
+func SchemaToGBNF(schema map[string]any) (string, error) {
+	g := &gbnfBuilder{
+		root:  schema,
+		rules: make(map[string]string),
+		refs:  make(map[string]string),
+	}
+	// Reserve the root rule name, so no other rule can take it.
+	g.rules["root"] = ""
+	expr, err := g.expr(schema, "root")
+	if err != nil {
+		return "", err
+	}
+	g.rules["root"] = expr
+	lines := []string{fmt.Sprintf("root ::= %s", g.rules["root"])}
+	for _, name := range sortedKeys(g.rules) {
+		if name != "root" {
+			lines = append(lines, fmt.Sprintf("%s ::= %s", name, g.rules[name]))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+type gbnfBuilder struct {
+	root  map[string]any
+	rules map[string]string
+	// refs maps each $ref that has been converted to the name of its rule.
+	refs map[string]string
+}
+
+// use adds a shared primitive rule to the grammar, returning its name.
+func (g *gbnfBuilder) use(name string) string {
+	if _, ok := g.rules[name]; !ok {
+		g.rules[name] = gbnfPrimitiveRules[name]
+		if name != "ws" {
+			g.use("ws")
+		}
+	}
+	return name
+}
+
+// rule adds a named rule for the schema node, returning the rule name.
+func (g *gbnfBuilder) rule(node map[string]any, name string) (string, error) {
+	name = g.uniqueName(name)
+	// Reserve the name first, in case the node refers back to itself.
+	g.rules[name] = ""
+	expr, err := g.expr(node, name)
+	if err != nil {
+		return "", err
+	}
+	g.rules[name] = expr
+	return name, nil
+}
+
+func (g *gbnfBuilder) uniqueName(name string) string {
+	name = strings.Trim(gbnfInvalidNameChars.ReplaceAllString(name, "-"), "-")
+	if name == "" {
+		name = "rule"
+	}
+	if _, taken := g.rules[name]; !taken {
+		if _, primitive := gbnfPrimitiveRules[name]; !primitive {
+			return name
+		}
+	}
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if _, taken := g.rules[candidate]; !taken {
+			return candidate
+		}
+	}
+}
+
+// expr converts a schema node into a GBNF expression, creating rules for any nested objects and arrays.
+func (g *gbnfBuilder) expr(node map[string]any, name string) (string, error) {
+	if ref, ok := node["$ref"].(string); ok {
+		if ruleName, ok := g.refs[ref]; ok {
+			return ruleName, nil
+		}
+		resolved, err := resolveSchemaRef(g.root, ref)
+		if err != nil {
+			return "", err
+		}
+		ruleName := g.uniqueName(ref[strings.LastIndex(ref, "/")+1:])
+		g.refs[ref] = ruleName
+		g.rules[ruleName] = ""
+		expr, err := g.expr(resolved, ruleName)
+		if err != nil {
+			return "", err
+		}
+		g.rules[ruleName] = expr
+		return ruleName, nil
+	}
+	if c, ok := node["const"]; ok {
+		return gbnfJSONLiteral(c, g.use("ws"))
+	}
+	if enum := schemaEnum(node); len(enum) > 0 {
+		alts := make([]string, len(enum))
+		for i, v := range enum {
+			lit, err := gbnfJSONLiteral(v, g.use("ws"))
+			if err != nil {
+				return "", err
+			}
+			alts[i] = lit
+		}
+		return fmt.Sprintf("( %s )", strings.Join(alts, " | ")), nil
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if options := schemaList(node, key); len(options) > 0 {
+			alts := make([]string, len(options))
+			for i, option := range options {
+				alt, err := g.subExpr(option, fmt.Sprintf("%s-%d", name, i))
+				if err != nil {
+					return "", err
+				}
+				alts[i] = alt
+			}
+			return fmt.Sprintf("( %s )", strings.Join(alts, " | ")), nil
+		}
+	}
+	types := schemaTypes(node)
+	if len(types) == 0 {
+		if _, ok := node["properties"]; ok {
+			types = []string{"object"}
+		} else {
+			return "", fmt.Errorf("cannot convert schema without a type to gbnf: %v", node)
+		}
+	}
+	alts := make([]string, len(types))
+	for i, t := range types {
+		var alt string
+		var err error
+		switch t {
+		case "object":
+			alt, err = g.object(node, name)
+		case "array":
+			alt, err = g.array(node, name)
+		case "string", "integer", "number", "boolean", "null":
+			alt = g.use(t)
+		default:
+			err = fmt.Errorf("cannot convert schema type %s to gbnf", t)
+		}
+		if err != nil {
+			return "", err
+		}
+		alts[i] = alt
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return fmt.Sprintf("( %s )", strings.Join(alts, " | ")), nil
+}
+
+// subExpr converts a nested schema node, giving objects and arrays their own rule to keep the grammar readable.
+func (g *gbnfBuilder) subExpr(node map[string]any, name string) (string, error) {
+	types := schemaTypes(node)
+	if _, ok := node["$ref"]; !ok && (slices.Contains(types, "object") || slices.Contains(types, "array")) {
+		return g.rule(node, name)
+	}
+	return g.expr(node, name)
+}
+
+func (g *gbnfBuilder) object(node map[string]any, name string) (string, error) {
+	ws := g.use("ws")
+	props, _ := node["properties"].(map[string]any)
+	required := schemaStrings(node, "required")
+	var requiredKVs, optionalKVs []string
+	for _, k := range sortedKeys(props) {
+		propSchema, ok := props[k].(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("property %s does not have a schema", k)
+		}
+		keyLit, err := gbnfJSONLiteral(k, ws)
+		if err != nil {
+			return "", err
+		}
+		valExpr, err := g.subExpr(propSchema, fmt.Sprintf("%s-%s", name, k))
+		if err != nil {
+			return "", err
+		}
+		kv := fmt.Sprintf(`%s ":" %s %s`, keyLit, ws, valExpr)
+		if slices.Contains(required, k) {
+			requiredKVs = append(requiredKVs, kv)
+		} else {
+			optionalKVs = append(optionalKVs, kv)
+		}
+	}
+	sep := fmt.Sprintf(` "," %s `, ws)
+	var body string
+	if len(requiredKVs) > 0 {
+		body = strings.Join(requiredKVs, sep)
+		for _, kv := range optionalKVs {
+			body += fmt.Sprintf(" (%s%s )?", sep, kv)
+		}
+	} else if len(optionalKVs) > 0 {
+		// Any optional property may come first, followed by any of the ones after it.
+		alts := make([]string, len(optionalKVs))
+		for i, kv := range optionalKVs {
+			alt := kv
+			for _, later := range optionalKVs[i+1:] {
+				alt += fmt.Sprintf(" (%s%s )?", sep, later)
+			}
+			alts[i] = alt
+		}
+		body = fmt.Sprintf("( %s )?", strings.Join(alts, " | "))
+	}
+	if body == "" {
+		return fmt.Sprintf(`"{" %s "}" %s`, ws, ws), nil
+	}
+	return fmt.Sprintf(`"{" %s %s "}" %s`, ws, body, ws), nil
+}
+
+func (g *gbnfBuilder) array(node map[string]any, name string) (string, error) {
+	ws := g.use("ws")
+	items, ok := node["items"].(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("array does not have an items schema")
+	}
+	item, err := g.subExpr(items, name+"-item")
+	if err != nil {
+		return "", err
+	}
+	minItems, _ := schemaInt(node, "minItems")
+	maxItems, hasMax := schemaInt(node, "maxItems")
+	if hasMax && maxItems <= 0 {
+		return fmt.Sprintf(`"[" %s "]" %s`, ws, ws), nil
+	}
+	next := fmt.Sprintf(`( "," %s %s )`, ws, item)
+	var rest string
+	switch {
+	case !hasMax:
+		rest = next + "*"
+	case maxItems-max(minItems, 1) > 0:
+		rest = fmt.Sprintf("%s{0,%d}", next, maxItems-max(minItems, 1))
+	}
+	if minItems > 1 {
+		rest = strings.TrimSpace(fmt.Sprintf("%s{%d} %s", next, minItems-1, rest))
+	}
+	elements := strings.TrimSpace(item + " " + rest)
+	if minItems == 0 {
+		elements = fmt.Sprintf("( %s )?", elements)
+	}
+	return fmt.Sprintf(`"[" %s %s "]" %s`, ws, elements, ws), nil
+}
+
+// gbnfJSONLiteral builds a GBNF expression matching exactly the json encoding of v.
+func gbnfJSONLiteral(v any, ws string) (string, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(string(bs))
+	return fmt.Sprintf(`"%s" %s`, escaped, ws), nil
+}
@@ -0,0 +1,203 @@
+package docqa
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSchemaToGBNF(t *testing.T) {
+	integerItems := map[string]any{"type": "integer"}
+	cases := []struct {
+		name   string
+		schema map[string]any
+		// rules are the expected rules of the grammar, other than the shared primitive rules.
+		rules map[string]string
+	}{
+		{
+			name:   "string",
+			schema: map[string]any{"type": "string"},
+			rules:  map[string]string{"root": `string`},
+		},
+		{
+			name:   "nullable",
+			schema: map[string]any{"type": []any{"string", "null"}},
+			rules:  map[string]string{"root": `( string | null )`},
+		},
+		{
+			name:   "enum with escapes",
+			schema: map[string]any{"enum": []any{"a", `b"c`}},
+			rules:  map[string]string{"root": `( "\"a\"" ws | "\"b\\\"c\"" ws )`},
+		},
+		{
+			name:   "const",
+			schema: map[string]any{"const": 3},
+			rules:  map[string]string{"root": `"3" ws`},
+		},
+		{
+			name:   "array",
+			schema: map[string]any{"type": "array", "items": integerItems},
+			rules:  map[string]string{"root": `"[" ws ( integer ( "," ws integer )* )? "]" ws`},
+		},
+		{
+			name:   "array with min and max",
+			schema: map[string]any{"type": "array", "items": integerItems, "minItems": 1, "maxItems": 3},
+			rules:  map[string]string{"root": `"[" ws integer ( "," ws integer ){0,2} "]" ws`},
+		},
+		{
+			name:   "array with min",
+			schema: map[string]any{"type": "array", "items": integerItems, "minItems": 2},
+			rules:  map[string]string{"root": `"[" ws integer ( "," ws integer ){1} ( "," ws integer )* "]" ws`},
+		},
+		{
+			name:   "empty array",
+			schema: map[string]any{"type": "array", "items": integerItems, "maxItems": 0},
+			rules:  map[string]string{"root": `"[" ws "]" ws`},
+		},
+		{
+			name: "required properties in sorted order",
+			schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"b": map[string]any{"type": "boolean"}, "a": map[string]any{"type": "number"}},
+				"required":   []any{"b", "a"},
+			},
+			rules: map[string]string{"root": `"{" ws "\"a\"" ws ":" ws number "," ws "\"b\"" ws ":" ws boolean "}" ws`},
+		},
+		{
+			name: "optional property after required",
+			schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"a": map[string]any{"type": "string"}, "b": map[string]any{"type": "string"}},
+				"required":   []any{"b"},
+			},
+			rules: map[string]string{"root": `"{" ws "\"b\"" ws ":" ws string ( "," ws "\"a\"" ws ":" ws string )? "}" ws`},
+		},
+		{
+			name: "only optional properties",
+			schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"a": map[string]any{"type": "string"}, "b": map[string]any{"type": "string"}},
+			},
+			rules: map[string]string{"root": `"{" ws ( "\"a\"" ws ":" ws string ( "," ws "\"b\"" ws ":" ws string )? | "\"b\"" ws ":" ws string )? "}" ws`},
+		},
+		{
+			name: "nested object with invalid rule name",
+			schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"x y": map[string]any{"type": "object", "properties": map[string]any{}}},
+				"required":   []any{"x y"},
+			},
+			rules: map[string]string{
+				"root":     `"{" ws "\"x y\"" ws ":" ws root-x-y "}" ws`,
+				"root-x-y": `"{" ws "}" ws`,
+			},
+		},
+		{
+			name: "any of",
+			schema: map[string]any{"anyOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "array", "items": map[string]any{"type": "null"}},
+			}},
+			rules: map[string]string{
+				"root":   `( string | root-1 )`,
+				"root-1": `"[" ws ( null ( "," ws null )* )? "]" ws`,
+			},
+		},
+		{
+			name: "shared ref",
+			schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"a": map[string]any{"$ref": "#/$defs/item"}, "b": map[string]any{"$ref": "#/$defs/item"}},
+				"required":   []any{"a", "b"},
+				"$defs":      map[string]any{"item": map[string]any{"type": "string"}},
+			},
+			rules: map[string]string{
+				"root": `"{" ws "\"a\"" ws ":" ws item "," ws "\"b\"" ws ":" ws item "}" ws`,
+				"item": `string`,
+			},
+		},
+		{
+			name: "recursive ref",
+			schema: map[string]any{
+				"$ref": "#/$defs/node",
+				"$defs": map[string]any{"node": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"next": map[string]any{"$ref": "#/$defs/node"}},
+				}},
+			},
+			rules: map[string]string{
+				"root": `node`,
+				"node": `"{" ws ( "\"next\"" ws ":" ws node )? "}" ws`,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			grammar, err := SchemaToGBNF(tc.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(grammar, "root ::= ") {
+				t.Errorf("expected the grammar to start with the root rule, got\n%s", grammar)
+			}
+			rules := parseGBNFRules(t, grammar)
+			for name, expr := range rules {
+				if primitive, ok := gbnfPrimitiveRules[name]; ok {
+					if expr != primitive {
+						t.Errorf("expected primitive rule %s to be %s, got %s", name, primitive, expr)
+					}
+					delete(rules, name)
+				}
+			}
+			if !reflect.DeepEqual(rules, tc.rules) {
+				t.Errorf("expected rules\n%v\ngot\n%v", tc.rules, rules)
+			}
+			// Every primitive rule that is used must be defined.
+			for name := range gbnfPrimitiveRules {
+				used := regexp.MustCompile(`(^|\s|\()` + name + `(\s|\)|$)`)
+				if used.MatchString(grammar) && !strings.Contains(grammar, "\n"+name+" ::= ") {
+					t.Errorf("rule %s is used but not defined in\n%s", name, grammar)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaToGBNFErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema map[string]any
+		err    string
+	}{
+		{"no type", map[string]any{}, "without a type"},
+		{"unknown type", map[string]any{"type": "date"}, "type date"},
+		{"array without items", map[string]any{"type": "array"}, "items schema"},
+		{"missing ref", map[string]any{"$ref": "#/$defs/missing"}, "#/$defs/missing"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := SchemaToGBNF(tc.schema)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// parseGBNFRules splits a grammar made by SchemaToGBNF into its rules.
+func parseGBNFRules(t *testing.T, grammar string) map[string]string {
+	t.Helper()
+	rules := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(grammar, "\n"), "\n") {
+		name, expr, ok := strings.Cut(line, " ::= ")
+		if !ok {
+			t.Fatalf("invalid rule %q", line)
+		}
+		if _, ok := rules[name]; ok {
+			t.Fatalf("rule %s is defined twice", name)
+		}
+		rules[name] = expr
+	}
+	return rules
+}
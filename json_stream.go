@@ -0,0 +1,167 @@
+package docqa
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonStreamObject is an object found by a [jsonStreamScanner], which is the value of an element of the array under key.
+type jsonStreamObject struct {
+	key string
+	raw []byte
+	// start is the byte offset of the object within everything fed to the scanner.
+	start int
+}
+
+// jsonStreamScanner incrementally scans json of the form `{"key": [{...}, {...}], ...}`, which may arrive in
+// arbitrarily split chunks, and reports each object inside the top-level arrays as soon as it closes.
+// It is tolerant of text before the first `{` and after the final `}` (such as markdown code fences).
+type jsonStreamScanner struct {
+	buf         []byte
+	pos         int
+	started     bool
+	finished    bool
+	stack       []byte
+	inString    bool
+	escaped     bool
+	stringStart int
+	lastString  string
+	currentKey  string
+	keys        []string
+	objectStart int
+}
+
+// Feed adds the next chunk of json, returning any objects that were completed by it.
+func (s *jsonStreamScanner) Feed(chunk string) ([]jsonStreamObject, error) {
+	s.buf = append(s.buf, chunk...)
+	var objects []jsonStreamObject
+	for ; s.pos < len(s.buf); s.pos++ {
+		if s.finished {
+			break
+		}
+		ch := s.buf[s.pos]
+		if !s.started {
+			if ch == '{' {
+				s.started = true
+				s.stack = append(s.stack, ch)
+			}
+			continue
+		}
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case ch == '\\':
+				s.escaped = true
+			case ch == '"':
+				s.inString = false
+				if len(s.stack) == 1 {
+					var str string
+					if err := json.Unmarshal(s.buf[s.stringStart:s.pos+1], &str); err != nil {
+						return objects, err
+					}
+					s.lastString = str
+				}
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			s.inString = true
+			s.stringStart = s.pos
+		case ':':
+			if len(s.stack) == 1 {
+				s.currentKey = s.lastString
+				s.keys = append(s.keys, s.currentKey)
+			}
+		case '{', '[':
+			s.stack = append(s.stack, ch)
+			if ch == '{' && len(s.stack) == 3 && s.stack[1] == '[' {
+				s.objectStart = s.pos
+			}
+		case '}', ']':
+			if len(s.stack) == 0 || !matchingBracket(s.stack[len(s.stack)-1], ch) {
+				return objects, fmt.Errorf("unexpected %c at position %d", ch, s.pos)
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+			if ch == '}' && len(s.stack) == 2 && s.stack[1] == '[' {
+				raw := make([]byte, s.pos+1-s.objectStart)
+				copy(raw, s.buf[s.objectStart:s.pos+1])
+				objects = append(objects, jsonStreamObject{key: s.currentKey, raw: raw, start: s.objectStart})
+			}
+			if len(s.stack) == 0 {
+				s.finished = true
+			}
+		}
+	}
+	return objects, nil
+}
+
+// Keys lists every top-level key seen so far.
+func (s *jsonStreamScanner) Keys() []string {
+	return s.keys
+}
+
+// Complete checks whether the top-level object has been closed.
+func (s *jsonStreamScanner) Complete() bool {
+	return s.finished
+}
+
+func matchingBracket(open, close byte) bool {
+	return (open == '{' && close == '}') || (open == '[' && close == ']')
+}
+
+// jsonObjectValueSpans finds the byte span of the value of each top-level key in a json object.
+func jsonObjectValueSpans(raw []byte) (map[string][2]int, error) {
+	spans := make(map[string][2]int)
+	depth := 0
+	inString, escaped := false, false
+	stringStart, valueStart := 0, -1
+	key, lastString := "", ""
+	endValue := func(end int) {
+		if valueStart >= 0 {
+			spans[key] = [2]int{valueStart, end}
+			valueStart = -1
+		}
+	}
+	for i, ch := range raw {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+				if depth == 1 && valueStart < 0 {
+					if err := json.Unmarshal(raw[stringStart:i+1], &lastString); err != nil {
+						return nil, err
+					}
+				}
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+			stringStart = i
+		case ':':
+			if depth == 1 {
+				key = lastString
+				valueStart = i + 1
+			}
+		case ',':
+			if depth == 1 {
+				endValue(i)
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				endValue(i)
+			}
+		}
+	}
+	return spans, nil
+}
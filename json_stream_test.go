@@ -0,0 +1,158 @@
+package docqa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONStreamScanner(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		objects  []jsonStreamObject
+		keys     []string
+		complete bool
+		err      string
+	}{
+		{
+			name:  "objects in arrays",
+			input: `{"a": [{"x": 1}, {"y": [2, {"z": 3}]}], "b": [{}]}`,
+			objects: []jsonStreamObject{
+				{key: "a", raw: []byte(`{"x": 1}`), start: 7},
+				{key: "a", raw: []byte(`{"y": [2, {"z": 3}]}`), start: 17},
+				{key: "b", raw: []byte(`{}`), start: 46},
+			},
+			keys:     []string{"a", "b"},
+			complete: true,
+		},
+		{
+			name:  "code fences around the json",
+			input: "```json\n{\"a\": [{\"x\": 1}]}\n```",
+			objects: []jsonStreamObject{
+				{key: "a", raw: []byte(`{"x": 1}`), start: 15},
+			},
+			keys:     []string{"a"},
+			complete: true,
+		},
+		{
+			name:  "brackets and quotes in strings",
+			input: `{"a\"]": [{"s": "}]\"{["}], "b": "not an array"}`,
+			objects: []jsonStreamObject{
+				{key: `a"]`, raw: []byte(`{"s": "}]\"{["}`), start: 10},
+			},
+			keys:     []string{`a"]`, "b"},
+			complete: true,
+		},
+		{
+			name:  "unicode keys",
+			input: `{"café ☕": [{"n": "é"}]}`,
+			objects: []jsonStreamObject{
+				{key: "café ☕", raw: []byte(`{"n": "é"}`), start: 15},
+			},
+			keys:     []string{"café ☕"},
+			complete: true,
+		},
+		{
+			name:     "objects that are not in an array are not reported",
+			input:    `{"a": {"b": [{"c": 1}]}, "d": []}`,
+			keys:     []string{"a", "d"},
+			complete: true,
+		},
+		{
+			name:  "incomplete",
+			input: `{"a": [{"x": 1}, {"y": `,
+			objects: []jsonStreamObject{
+				{key: "a", raw: []byte(`{"x": 1}`), start: 7},
+			},
+			keys: []string{"a"},
+		},
+		{
+			name:     "text after the end is ignored",
+			input:    `{"a": []} {"b": [{}]}`,
+			keys:     []string{"a"},
+			complete: true,
+		},
+		{
+			name:  "mismatched bracket",
+			input: `{"a": [{"x": 1]]}`,
+			err:   "unexpected ] at position 14",
+		},
+	}
+	for _, tc := range cases {
+		// The result must not depend on how the input is split into chunks.
+		for _, chunkSize := range []int{len(tc.input), 1, 3} {
+			s := &jsonStreamScanner{}
+			var objects []jsonStreamObject
+			var err error
+			for i := 0; i < len(tc.input) && err == nil; i += chunkSize {
+				var found []jsonStreamObject
+				found, err = s.Feed(tc.input[i:min(i+chunkSize, len(tc.input))])
+				objects = append(objects, found...)
+			}
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("%s (chunks of %d): expected error %q, got %v", tc.name, chunkSize, tc.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s (chunks of %d): %v", tc.name, chunkSize, err)
+				continue
+			}
+			if !reflect.DeepEqual(objects, tc.objects) {
+				t.Errorf("%s (chunks of %d): expected objects %v, got %v", tc.name, chunkSize, tc.objects, objects)
+			}
+			for _, o := range objects {
+				if got := tc.input[o.start : o.start+len(o.raw)]; got != string(o.raw) {
+					t.Errorf("%s (chunks of %d): object starts at the wrong offset, found %q", tc.name, chunkSize, got)
+				}
+			}
+			if !reflect.DeepEqual(s.Keys(), tc.keys) {
+				t.Errorf("%s (chunks of %d): expected keys %v, got %v", tc.name, chunkSize, tc.keys, s.Keys())
+			}
+			if s.Complete() != tc.complete {
+				t.Errorf("%s (chunks of %d): expected complete to be %v", tc.name, chunkSize, tc.complete)
+			}
+		}
+	}
+}
+
+func TestJSONObjectValueSpans(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		spans map[string]string
+	}{
+		{
+			name:  "flat",
+			input: `{"a": 1, "b": "x,y", "c": true}`,
+			spans: map[string]string{"a": " 1", "b": ` "x,y"`, "c": " true"},
+		},
+		{
+			name:  "nested",
+			input: `{"a": {"b": [1, 2]}, "c": [{"d": ":"}]}`,
+			spans: map[string]string{"a": ` {"b": [1, 2]}`, "c": ` [{"d": ":"}]`},
+		},
+		{
+			name:  "string values that look like keys",
+			input: `{"a": "b", "c": "d"}`,
+			spans: map[string]string{"a": ` "b"`, "c": ` "d"`},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spans, err := jsonObjectValueSpans([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := make(map[string]string, len(spans))
+			for k, span := range spans {
+				values[k] = tc.input[span[0]:span[1]]
+			}
+			if !reflect.DeepEqual(values, tc.spans) {
+				t.Errorf("expected values %q, got %q", tc.spans, values)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package docqa
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	lineStartKey = "line_start"
+	lineEndKey   = "line_end"
+)
+
+// WithLineLocalisation sends the document to the LLM with a number marker at the start of every line,
+// asks for the lines each answer appears on, and maps those lines back to [EntityAttributes.LocalisedRange]
+// (in byte offsets of the original document text).
+func WithLineLocalisation() BasicProtocolOption {
+	return func(qa *basicProtocol) {
+		qa.extensions = append(qa.extensions, lineLocalisationExtension{})
+	}
+}
+
+// NewLocalisedProtocol creates a protocol like [NewBasicProtocol], which also locates each answer in the document (see [WithLineLocalisation]).
+func NewLocalisedProtocol(roleTask RoleAndTask, types map[string]Type) Protocol {
+	return NewBasicProtocol(roleTask, types, WithLineLocalisation())
+}
+
+// documentFormatter is an [answerExtension] that also changes how the document is presented to the LLM.
+type documentFormatter interface {
+	formatDocument(documentText string) string
+}
+
+type lineLocalisationExtension struct{}
+
+func (lineLocalisationExtension) schemaProperties() map[string]any {
+	return map[string]any{
+		lineStartKey: map[string]any{"type": "integer"},
+		lineEndKey:   map[string]any{"type": "integer"},
+	}
+}
+
+func (lineLocalisationExtension) instructions() []string {
+	return []string{
+		"Each line of the document starts with a marker of its line number, such as `[L12]`, which is not part of the document",
+		fmt.Sprintf("Every answer object must include `%s` and `%s`, the numbers of the first and last lines that the answer was found on", lineStartKey, lineEndKey),
+		"If the answer is on a single line, the start and end lines should be the same",
+	}
+}
+
+func (lineLocalisationExtension) formatDocument(documentText string) string {
+	lines := strings.Split(documentText, "\n")
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("[L%d] %s", i+1, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (lineLocalisationExtension) apply(entity Entity, answer map[string]any, documentText string) error {
+	start, okStart := answer[lineStartKey].(float64)
+	end, okEnd := answer[lineEndKey].(float64)
+	if !okStart || !okEnd {
+		return nil
+	}
+	entity.Attr().LocalisedRange = lineRange(documentText, int(start), int(end))
+	return nil
+}
+
+// lineRange converts a range of 1-based line numbers (inclusive) to a range of byte offsets,
+// clamping the lines to the document, or returning an indefinite range if they are not in the document at all.
+// Line breaks at the end of the range, such as the one that ends the document, are not included.
+func lineRange(documentText string, startLine, endLine int) Range {
+	if startLine > endLine {
+		startLine, endLine = endLine, startLine
+	}
+	starts := []int{0}
+	for i := 0; i < len(documentText); i++ {
+		if documentText[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	if endLine < 1 || startLine > len(starts) {
+		return IndefRange()
+	}
+	startLine, endLine = max(startLine, 1), min(endLine, len(starts))
+	end := len(documentText)
+	if endLine < len(starts) {
+		end = starts[endLine] - 1
+	}
+	end = max(starts[startLine-1], len(strings.TrimRight(documentText[:end], "\r\n")))
+	return Range{Start: starts[startLine-1], End: end}
+}
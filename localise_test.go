@@ -0,0 +1,62 @@
+package docqa
+
+import (
+	"testing"
+)
+
+func TestLineRange(t *testing.T) {
+	// Lines start at 0, 6, 14, and 20, where the last line is the empty one after the final line break.
+	doc := "first\nsecond\r\nthird\n"
+	cases := []struct {
+		name       string
+		doc        string
+		start, end int
+		want       Range
+		text       string
+	}{
+		{name: "single line", doc: doc, start: 1, end: 1, want: Range{0, 5}, text: "first"},
+		{name: "carriage return is excluded", doc: doc, start: 2, end: 2, want: Range{6, 12}, text: "second"},
+		{name: "several lines", doc: doc, start: 1, end: 3, want: Range{0, 19}, text: "first\nsecond\r\nthird"},
+		{name: "reversed", doc: doc, start: 3, end: 1, want: Range{0, 19}, text: "first\nsecond\r\nthird"},
+		{name: "end past the last line", doc: doc, start: 2, end: 10, want: Range{6, 19}, text: "second\r\nthird"},
+		{name: "empty last line", doc: doc, start: 4, end: 4, want: Range{20, 20}, text: ""},
+		{name: "start before the first line", doc: doc, start: -2, end: 1, want: Range{0, 5}, text: "first"},
+		{name: "before the document", doc: doc, start: -3, end: 0, want: IndefRange()},
+		{name: "after the document", doc: doc, start: 5, end: 6, want: IndefRange()},
+		{name: "no final line break", doc: "a\nb", start: 2, end: 9, want: Range{2, 3}, text: "b"},
+		{name: "ends on an empty line", doc: "a\n\nb", start: 1, end: 2, want: Range{0, 1}, text: "a"},
+		{name: "only an empty line", doc: "a\n\nb", start: 2, end: 2, want: Range{2, 2}, text: ""},
+		{name: "empty document", doc: "", start: 1, end: 3, want: Range{0, 0}, text: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := lineRange(tc.doc, tc.start, tc.end)
+			if got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			if !got.IsIndef() && tc.doc[got.Start:got.End] != tc.text {
+				t.Errorf("expected the range to cover %q, got %q", tc.text, tc.doc[got.Start:got.End])
+			}
+		})
+	}
+}
+
+func TestLineLocalisation(t *testing.T) {
+	qa := newTestTextProtocol(WithLineLocalisation()).(DocumentProtocol)
+	doc := "Title\nBy Ann\n"
+	if want := "[L1] Title\n[L2] By Ann\n[L3] "; qa.UserPrompt(doc) != want {
+		t.Errorf("expected user prompt %q, got %q", want, qa.UserPrompt(doc))
+	}
+	answers, err := qa.ParseDocumentResponse(`{"q":[
+		{"answer_type":"text","text":"Ann","line_start":2,"line_end":2},
+		{"answer_type":"text","text":"all","line_start":1,"line_end":3}
+	]}`, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []Range{{6, 12}, {0, 12}} {
+		if got := answers["q"][i].Attr().LocalisedRange; got != want {
+			t.Errorf("answer %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
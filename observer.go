@@ -0,0 +1,132 @@
+package docqa
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CallTrace describes a single LLM call, as reported to an [Observer].
+type CallTrace struct {
+	SystemPrompt string
+	UserPrompt   string
+	Schema       map[string]any
+	// Response is the raw response text, which may be empty if the call failed.
+	Response string
+	Latency  time.Duration
+	Usage    LLMUsage
+	// Err is the error the call failed with, if any.
+	Err error
+}
+
+// Observer is notified of LLM calls, for example to log or trace them.
+// Observers may be called concurrently.
+type Observer interface {
+	// ObserveCall is called once each call has finished.
+	ObserveCall(ctx context.Context, trace CallTrace)
+}
+
+type observedClient struct {
+	client   Client
+	observer Observer
+}
+
+// NewObservedClient wraps a [Client] so that the observer is notified of every call made through it.
+func NewObservedClient(client Client, observer Observer) ContextClient {
+	return &observedClient{
+		client:   client,
+		observer: observer,
+	}
+}
+
+// GetLLMResponse implements [Client].
+func (c *observedClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return c.GetLLMResponseContext(context.Background(), systemPrompt, userPrompt, schema)
+}
+
+// GetLLMResponseContext implements [ContextClient].
+func (c *observedClient) GetLLMResponseContext(ctx context.Context, systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	start := time.Now()
+	resp, usage, err := GetLLMResponseWithContext(ctx, c.client, systemPrompt, userPrompt, schema)
+	c.observer.ObserveCall(ctx, CallTrace{
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Schema:       schema,
+		Response:     resp,
+		Latency:      time.Since(start),
+		Usage:        usage,
+		Err:          err,
+	})
+	return resp, usage, err
+}
+
+type slogObserver struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogObserver creates an [Observer] that logs every call to the logger.
+// Successful calls are logged at the given level, and failed calls at [slog.LevelError].
+func NewSlogObserver(logger *slog.Logger, level slog.Level) Observer {
+	return &slogObserver{
+		logger: logger,
+		level:  level,
+	}
+}
+
+// ObserveCall implements [Observer].
+func (o *slogObserver) ObserveCall(ctx context.Context, trace CallTrace) {
+	schema, _ := json.Marshal(trace.Schema)
+	attrs := []slog.Attr{
+		slog.String("system_prompt", trace.SystemPrompt),
+		slog.String("user_prompt", trace.UserPrompt),
+		slog.String("schema", string(schema)),
+		slog.String("response", trace.Response),
+		slog.Duration("latency", trace.Latency),
+		slog.Int("input_tokens", trace.Usage.InputTokens),
+		slog.Int("output_tokens", trace.Usage.OutputTokens),
+	}
+	if trace.Err != nil {
+		attrs = append(attrs, slog.String("error", trace.Err.Error()))
+		o.logger.LogAttrs(ctx, slog.LevelError, "llm call failed", attrs...)
+		return
+	}
+	o.logger.LogAttrs(ctx, o.level, "llm call", attrs...)
+}
+
+type jsonlObserver struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+// NewJSONLObserver creates an [Observer] that writes every call as a line of json to w, such as a trace file.
+func NewJSONLObserver(w io.Writer) Observer {
+	return &jsonlObserver{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// ObserveCall implements [Observer].
+func (o *jsonlObserver) ObserveCall(ctx context.Context, trace CallTrace) {
+	errStr := ""
+	if trace.Err != nil {
+		errStr = trace.Err.Error()
+	}
+	line := map[string]any{
+		"time":          time.Now().UTC().Format(time.RFC3339Nano),
+		"system_prompt": trace.SystemPrompt,
+		"user_prompt":   trace.UserPrompt,
+		"schema":        trace.Schema,
+		"response":      trace.Response,
+		"latency_ms":    trace.Latency.Milliseconds(),
+		"usage":         trace.Usage,
+		"error":         errStr,
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	// An observer has nowhere to report its own errors, and tracing must never break extraction.
+	_ = o.enc.Encode(line)
+}
@@ -0,0 +1,112 @@
+package docqa
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+// observedCalls makes a successful and then a failing call through an observed client.
+func observedCalls(t *testing.T, observer Observer) {
+	t.Helper()
+	client := &stubClient{respond: func(call int, _ string, _ []Message) (string, LLMUsage, error) {
+		if call == 0 {
+			return `{"a":1}`, LLMUsage{InputTokens: 10, OutputTokens: 2}, nil
+		}
+		return "", LLMUsage{InputTokens: 10}, errors.New("offline")
+	}}
+	observed := NewObservedClient(client, observer)
+	schema := map[string]any{"type": "object"}
+	if _, _, err := observed.GetLLMResponse("system", "user", schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := observed.GetLLMResponse("system", "user", schema); err == nil {
+		t.Fatal("expected the second call to fail")
+	}
+}
+
+func TestJSONLObserver(t *testing.T) {
+	var buf bytes.Buffer
+	observedCalls(t, NewJSONLObserver(&buf))
+
+	type traceLine struct {
+		Time         string         `json:"time"`
+		SystemPrompt string         `json:"system_prompt"`
+		UserPrompt   string         `json:"user_prompt"`
+		Schema       map[string]any `json:"schema"`
+		Response     string         `json:"response"`
+		LatencyMS    *int64         `json:"latency_ms"`
+		Usage        LLMUsage       `json:"usage"`
+		Error        string         `json:"error"`
+	}
+	var lines []traceLine
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line traceLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %q is not json: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected a line per call, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if line.Time == "" || line.LatencyMS == nil || line.SystemPrompt != "system" || line.UserPrompt != "user" || line.Schema["type"] != "object" {
+			t.Errorf("line %d is missing details of the call: %+v", i, line)
+		}
+	}
+	if lines[0].Response != `{"a":1}` || lines[0].Usage != (LLMUsage{InputTokens: 10, OutputTokens: 2}) || lines[0].Error != "" {
+		t.Errorf("unexpected line for the successful call: %+v", lines[0])
+	}
+	if lines[1].Response != "" || lines[1].Usage != (LLMUsage{InputTokens: 10}) || lines[1].Error != "offline" {
+		t.Errorf("unexpected line for the failed call: %+v", lines[1])
+	}
+}
+
+func TestSlogObserver(t *testing.T) {
+	cases := []struct {
+		name         string
+		handlerLevel slog.Level
+		level        slog.Level
+		// levels are the levels of the records that should be logged.
+		levels []string
+	}{
+		{name: "success at the given level", handlerLevel: slog.LevelDebug, level: slog.LevelInfo, levels: []string{"INFO", "ERROR"}},
+		{name: "failures are logged when successes are filtered", handlerLevel: slog.LevelWarn, level: slog.LevelDebug, levels: []string{"ERROR"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: tc.handlerLevel}))
+			observedCalls(t, NewSlogObserver(logger, tc.level))
+
+			var records []map[string]any
+			for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+				record := make(map[string]any)
+				if err := json.Unmarshal(line, &record); err != nil {
+					t.Fatalf("record %q is not json: %v", line, err)
+				}
+				records = append(records, record)
+			}
+			if len(records) != len(tc.levels) {
+				t.Fatalf("expected %d records, got %d: %s", len(tc.levels), len(records), buf.String())
+			}
+			for i, record := range records {
+				if record["level"] != tc.levels[i] {
+					t.Errorf("record %d: expected level %s, got %v", i, tc.levels[i], record["level"])
+				}
+				if record["input_tokens"] != 10.0 || record["schema"] != `{"type":"object"}` {
+					t.Errorf("record %d is missing details of the call: %v", i, record)
+				}
+			}
+			failure := records[len(records)-1]
+			if failure["msg"] != "llm call failed" || failure["error"] != "offline" {
+				t.Errorf("unexpected record for the failed call: %v", failure)
+			}
+		})
+	}
+}
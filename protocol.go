@@ -1,5 +1,10 @@
 package docqa
 
+import (
+	"context"
+	"time"
+)
+
 // Protocol defines a method of communication to and from the LLM.
 type Protocol interface {
 	// Schema creates a jsonschema given a set of keyed questions.
@@ -10,6 +15,47 @@ type Protocol interface {
 	ParseResponse(resp string) (map[string][]Entity, error)
 }
 
+// StreamingProtocol is a [Protocol] that can parse a response incrementally, while it is still being generated.
+type StreamingProtocol interface {
+	Protocol
+	// NewStreamParser creates a [StreamParser] to parse a single response about the document.
+	NewStreamParser(documentText string) StreamParser
+}
+
+// DocumentProtocol is a [Protocol] that needs to see the document text,
+// either to build the user prompt or to locate the answers within the document.
+type DocumentProtocol interface {
+	Protocol
+	// UserPrompt builds the user prompt that presents the document to the LLM.
+	UserPrompt(documentText string) string
+	// ParseDocumentResponse is the same as [Protocol.ParseResponse], but can use the document text to fill in [EntityAttributes].
+	ParseDocumentResponse(resp string, documentText string) (map[string][]Entity, error)
+}
+
+// LogprobProtocol is a [DocumentProtocol] that can use the log probabilities of the response tokens,
+// for example to estimate the confidence of each answer.
+type LogprobProtocol interface {
+	DocumentProtocol
+	// WantsLogprobs reports whether the protocol would make use of logprobs.
+	WantsLogprobs() bool
+	// ParseLogprobResponse is the same as [DocumentProtocol.ParseDocumentResponse], but also has the logprobs of the response tokens.
+	ParseLogprobResponse(resp string, documentText string, logprobs []TokenLogprob) (map[string][]Entity, error)
+}
+
+// StreamParser incrementally parses a single response.
+type StreamParser interface {
+	// Feed adds the next piece of the response, returning any entities that were completed by it.
+	Feed(chunk string) ([]StreamedEntity, error)
+	// Finish checks the response was complete, and returns every parsed [Entity], keyed by question key.
+	Finish() (map[string][]Entity, error)
+}
+
+// StreamedEntity is an [Entity] that was parsed from a partial response, tagged with the key of the question it answers.
+type StreamedEntity struct {
+	QuestionKey string
+	Entity      Entity
+}
+
 // RoleAndTask defines a role and a task for the LLM,
 // both of which should be short (~ 1 sentence).
 type RoleAndTask struct {
@@ -19,23 +65,145 @@ type RoleAndTask struct {
 
 // ExtractAnswers answers the given [Question]s about a document,
 // returning lists of [Entity] keyed by question key.
-func ExtractAnswers(client Client, qa Protocol, questions map[string]Question, documentText string) (map[string][]Entity, LLMUsage, error) {
-	resp, usage, err := client.GetLLMResponse(
-		qa.SystemPrompt(questions),
-		documentText,
-		qa.Schema(questions),
-	)
-	if err != nil {
-		return nil, usage, err
+func ExtractAnswers(client Client, qa Protocol, questions map[string]Question, documentText string, opts ...ExtractOption) (map[string][]Entity, LLMUsage, error) {
+	return ExtractAnswersContext(context.Background(), client, qa, questions, documentText, opts...)
+}
+
+// ExtractAnswersContext is the same as [ExtractAnswers], but stops as soon as ctx is done.
+// The context is passed through to the client if it is a [ContextClient].
+// If [WithRepairAttempts] is given, responses that cannot be parsed are sent back to the model to be corrected,
+// and the returned usage covers every turn.
+func ExtractAnswersContext(ctx context.Context, client Client, qa Protocol, questions map[string]Question, documentText string, opts ...ExtractOption) (map[string][]Entity, LLMUsage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, LLMUsage{}, err
+	}
+	if err := validateQuestions(questions); err != nil {
+		return nil, LLMUsage{}, err
 	}
+	cfg := newExtractConfig(opts)
+	systemPrompt, schema := qa.SystemPrompt(questions), qa.Schema(questions)
+	messages := userMessages(protocolUserPrompt(qa, documentText))
+	var totalUsage LLMUsage
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		resp, usage, answers, parseErr, err := callAndParse(ctx, client, qa, questions, systemPrompt, messages, schema, documentText)
+		cfg.observe(ctx, start, systemPrompt, flattenConversation(messages), schema, resp, usage, err)
+		totalUsage = totalUsage.Add(usage)
+		if err == nil {
+			return answers, totalUsage, nil
+		}
+		// Only responses that the model wrote, but got wrong, can be repaired.
+		// Errors from the client (even malformed ones) have nothing to send back.
+		if attempt >= cfg.repairAttempts || parseErr == nil || resp == "" {
+			return nil, totalUsage, err
+		}
+		messages = append(messages,
+			Message{Role: AssistantRole, Content: resp},
+			Message{Role: UserRole, Content: repairPrompt(resp, schema, parseErr)},
+		)
+	}
+}
 
-	answers, err := qa.ParseResponse(resp)
+// ExtractAnswersStream is the same as [ExtractAnswersContext], but each [Entity] is sent on the entities
+// channel as soon as it has been generated. The entities channel is closed before this function returns,
+// and the complete answers are also returned once the response has finished.
+func ExtractAnswersStream(ctx context.Context, client StreamingClient, qa StreamingProtocol, questions map[string]Question, documentText string, entities chan<- StreamedEntity, opts ...ExtractOption) (map[string][]Entity, LLMUsage, error) {
+	defer close(entities)
+	if err := ctx.Err(); err != nil {
+		return nil, LLMUsage{}, err
+	}
+	if err := validateQuestions(questions); err != nil {
+		return nil, LLMUsage{}, err
+	}
+	cfg := newExtractConfig(opts)
+	systemPrompt, schema := qa.SystemPrompt(questions), qa.Schema(questions)
+	userPrompt := protocolUserPrompt(qa, documentText)
+	parser := qa.NewStreamParser(documentText)
+	start := time.Now()
+	resp, usage, err := client.StreamLLMResponse(ctx, systemPrompt, userPrompt, schema, func(chunk string) error {
+		completed, err := parser.Feed(chunk)
+		if err != nil {
+			return err
+		}
+		for _, e := range completed {
+			select {
+			case entities <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	var answers map[string][]Entity
+	if err == nil {
+		answers, err = parser.Finish()
+		if err == nil {
+			err = ValidateCardinality(questions, answers)
+		}
+		if err != nil {
+			err = newMalformedResponseError(resp, usage, err)
+		}
+	}
+	cfg.observe(ctx, start, systemPrompt, userPrompt, schema, resp, usage, err)
 	if err != nil {
 		return nil, usage, err
 	}
 	return answers, usage, nil
 }
 
+// callAndParse prompts the client with the conversation and parses its response with the protocol,
+// using token logprobs if the protocol wants them and the client can provide them,
+// and checks the answers against the [Question]s with [ValidateCardinality].
+// Logprobs are only requested for the first turn, as a [LogprobClient] cannot continue a conversation.
+// If the response was received but could not be parsed, or was invalid, parseErr is the reason,
+// and err is a [*MalformedResponseError] wrapping it. Errors from the client only set err.
+func callAndParse(ctx context.Context, client Client, qa Protocol, questions map[string]Question, systemPrompt string, messages []Message, schema map[string]any, documentText string) (resp string, usage LLMUsage, answers map[string][]Entity, parseErr error, err error) {
+	parse := func() (map[string][]Entity, error) {
+		return protocolParseResponse(qa, resp, documentText)
+	}
+	lpProtocol, lpProtocolOK := qa.(LogprobProtocol)
+	lpClient, lpClientOK := client.(LogprobClient)
+	if lpProtocolOK && lpClientOK && lpProtocol.WantsLogprobs() && len(messages) == 1 {
+		var logprobs []TokenLogprob
+		resp, logprobs, usage, err = lpClient.GetLLMResponseLogprobs(ctx, systemPrompt, messages[0].Content, schema)
+		parse = func() (map[string][]Entity, error) {
+			return lpProtocol.ParseLogprobResponse(resp, documentText, logprobs)
+		}
+	} else {
+		resp, usage, err = GetLLMConversationResponseWithContext(ctx, client, systemPrompt, messages, schema)
+	}
+	if err != nil {
+		return resp, usage, nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return resp, usage, nil, nil, err
+	}
+	answers, parseErr = parse()
+	if parseErr == nil {
+		parseErr = ValidateCardinality(questions, answers)
+	}
+	if parseErr != nil {
+		return resp, usage, nil, parseErr, newMalformedResponseError(resp, usage, parseErr)
+	}
+	return resp, usage, answers, nil, nil
+}
+
+// protocolUserPrompt builds the user prompt for the document, using the protocol if it is a [DocumentProtocol].
+func protocolUserPrompt(qa Protocol, documentText string) string {
+	if dp, ok := qa.(DocumentProtocol); ok {
+		return dp.UserPrompt(documentText)
+	}
+	return documentText
+}
+
+// protocolParseResponse parses a response about the document, using the protocol's document-aware parsing if it is a [DocumentProtocol].
+func protocolParseResponse(qa Protocol, resp string, documentText string) (map[string][]Entity, error) {
+	if dp, ok := qa.(DocumentProtocol); ok {
+		return dp.ParseDocumentResponse(resp, documentText)
+	}
+	return qa.ParseResponse(resp)
+}
+
 // GetDefaultRoleAndTask builds a [RoleAndTask] for a generic document information extraction task.
 func GetDefaultRoleAndTask() RoleAndTask {
 	return RoleAndTask{
@@ -9,14 +9,47 @@ import (
 type basicProtocol struct {
 	roleAndTask RoleAndTask
 	types       map[string]Type
+	extensions  []answerExtension
+	// logprobConfidence derives confidence from token logprobs, see [WithLogprobConfidence].
+	logprobConfidence bool
+	// omitCardinality leaves answer counts out of the schema, see [WithoutCardinalitySchema].
+	omitCardinality bool
+}
+
+// BasicProtocolOption configures a [Protocol] created with [NewBasicProtocol].
+type BasicProtocolOption func(*basicProtocol)
+
+// answerExtension asks the LLM for extra properties on every answer object,
+// and uses them to fill in the attributes of the parsed [Entity].
+type answerExtension interface {
+	// schemaProperties are added to the schema of every answer type.
+	schemaProperties() map[string]any
+	// instructions are added to the system prompt, as bullet points.
+	instructions() []string
+	// apply updates the entity using the extra properties of the answer.
+	apply(entity Entity, answer map[string]any, documentText string) error
 }
 
 // NewBasicProtocol creates a sensible and generalised protocol for information extraction.
-func NewBasicProtocol(roleTask RoleAndTask, types map[string]Type) Protocol {
-	return &basicProtocol{
+// The returned protocol is also a [StreamingProtocol], a [DocumentProtocol] and a [LogprobProtocol].
+func NewBasicProtocol(roleTask RoleAndTask, types map[string]Type, opts ...BasicProtocolOption) Protocol {
+	qa := &basicProtocol{
 		roleAndTask: roleTask,
 		types:       types,
 	}
+	for _, opt := range opts {
+		opt(qa)
+	}
+	return qa
+}
+
+// WithoutCardinalitySchema stops [Question.MinAnswers] and [Question.MaxAnswers] being enforced in the schema
+// with `minItems` and `maxItems`, for providers that do not support those keywords.
+// The limits are still stated in the system prompt and checked after parsing.
+func WithoutCardinalitySchema() BasicProtocolOption {
+	return func(qa *basicProtocol) {
+		qa.omitCardinality = true
+	}
 }
 
 // Schema implements [Protocol].
@@ -24,20 +57,21 @@ func (qa *basicProtocol) Schema(qs map[string]Question) map[string]any {
 	properties := make(map[string]any)
 	components := make(map[string]any)
 
-	for key, parser := range qa.types {
-		schemaProps := parser.SchemaProperties()
+	for _, key := range sortedKeys(qa.types) {
+		schemaProps := qa.types[key].SchemaProperties()
 		schemaProps["answer_type"] = map[string]any{
 			"const": key,
 			"type":  "string",
 		}
-		schemaPropsKeys := make([]string, 0, len(schemaProps))
-		for k := range schemaProps {
-			schemaPropsKeys = append(schemaPropsKeys, k)
+		for _, ext := range qa.extensions {
+			for k, v := range ext.schemaProperties() {
+				schemaProps[k] = v
+			}
 		}
 		components[key] = map[string]any{
 			"type":                 "object",
 			"properties":           schemaProps,
-			"required":             schemaPropsKeys,
+			"required":             sortedKeys(schemaProps),
 			"additionalProperties": false,
 		}
 	}
@@ -52,61 +86,145 @@ func (qa *basicProtocol) Schema(qs map[string]Question) map[string]any {
 		one := map[string]any{
 			"anyOf": options,
 		}
-		properties[key] = map[string]any{
+		answers := map[string]any{
 			"type":  "array",
 			"items": one,
 		}
-	}
-
-	pkeys := make([]string, 0, len(properties))
-	for k := range properties {
-		pkeys = append(pkeys, k)
+		if !qa.omitCardinality {
+			if question.MinAnswers > 0 {
+				answers["minItems"] = question.MinAnswers
+			}
+			if question.MaxAnswers > 0 {
+				answers["maxItems"] = question.MaxAnswers
+			}
+		}
+		properties[key] = answers
 	}
 
 	return map[string]any{
 		"type":                 "object",
 		"properties":           properties,
 		"definitions":          components,
-		"required":             pkeys,
+		"required":             sortedKeys(properties),
 		"additionalProperties": false,
 	}
 }
 
 // ParseResponse implements [Protocol].
 func (qa *basicProtocol) ParseResponse(resp string) (map[string][]Entity, error) {
+	return qa.ParseDocumentResponse(resp, "")
+}
+
+// UserPrompt implements [DocumentProtocol].
+func (qa *basicProtocol) UserPrompt(documentText string) string {
+	for _, ext := range qa.extensions {
+		if f, ok := ext.(documentFormatter); ok {
+			documentText = f.formatDocument(documentText)
+		}
+	}
+	return documentText
+}
+
+// ParseDocumentResponse implements [DocumentProtocol].
+func (qa *basicProtocol) ParseDocumentResponse(resp string, documentText string) (map[string][]Entity, error) {
 	respTyped := make(map[string][]map[string]any)
 	err := json.Unmarshal([]byte(resp), &respTyped)
 	if err != nil {
 		return nil, err
 	}
 	answers := make(map[string][]Entity)
-	for qKey, qAnswers := range respTyped {
+	for _, qKey := range sortedKeys(respTyped) {
 		answers[qKey] = []Entity{}
-		for _, qAnswer := range qAnswers {
-			answerType, ok := qAnswer["answer_type"]
-			if !ok {
-				return nil, fmt.Errorf("answer did not have answer_type key")
-			}
-			answerTypeStr, ok := answerType.(string)
-			if !ok {
-				return nil, fmt.Errorf("answer type was not a string")
-			}
-			parser, ok := qa.types[answerTypeStr]
-			if !ok {
-				return nil, fmt.Errorf("did not have a parser for that")
-			}
-			entity, err := parser.Parse(qAnswer)
+		for i, qAnswer := range respTyped[qKey] {
+			entity, err := qa.parseAnswer(qAnswer, documentText)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("answer %d to question %q: %w", i, qKey, err)
 			}
-			entity.Attr().LocalisedRange = IndefRange()
-			entity.Attr().EvidenceRanges = make([]Range, 0)
 			answers[qKey] = append(answers[qKey], entity)
 		}
 	}
 	return answers, nil
 }
 
+// NewStreamParser implements [StreamingProtocol].
+func (qa *basicProtocol) NewStreamParser(documentText string) StreamParser {
+	return &basicStreamParser{
+		qa:           qa,
+		documentText: documentText,
+		answers:      make(map[string][]Entity),
+	}
+}
+
+// parseAnswer parses a single answer object into an [Entity] using the [Type] named by its answer_type.
+func (qa *basicProtocol) parseAnswer(qAnswer map[string]any, documentText string) (Entity, error) {
+	answerType, ok := qAnswer["answer_type"]
+	if !ok {
+		return nil, fmt.Errorf("answer did not have an answer_type key")
+	}
+	answerTypeStr, ok := answerType.(string)
+	if !ok {
+		return nil, fmt.Errorf("answer_type was a %T, not a string", answerType)
+	}
+	parser, ok := qa.types[answerTypeStr]
+	if !ok {
+		return nil, fmt.Errorf("unknown answer_type %q, expected one of %s", answerTypeStr, strings.Join(sortedKeys(qa.types), ", "))
+	}
+	entity, err := parser.Parse(qAnswer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s answer: %w", answerTypeStr, err)
+	}
+	entity.Attr().LocalisedRange = IndefRange()
+	entity.Attr().EvidenceRanges = make([]Range, 0)
+	for _, ext := range qa.extensions {
+		if err := ext.apply(entity, qAnswer, documentText); err != nil {
+			return nil, err
+		}
+	}
+	return entity, nil
+}
+
+type basicStreamParser struct {
+	qa           *basicProtocol
+	documentText string
+	scanner      jsonStreamScanner
+	answers      map[string][]Entity
+}
+
+// Feed implements [StreamParser].
+func (p *basicStreamParser) Feed(chunk string) ([]StreamedEntity, error) {
+	objects, err := p.scanner.Feed(chunk)
+	if err != nil {
+		return nil, err
+	}
+	streamed := make([]StreamedEntity, 0, len(objects))
+	for _, obj := range objects {
+		qAnswer := make(map[string]any)
+		if err := json.Unmarshal(obj.raw, &qAnswer); err != nil {
+			return nil, err
+		}
+		entity, err := p.qa.parseAnswer(qAnswer, p.documentText)
+		if err != nil {
+			return nil, fmt.Errorf("answer %d to question %q: %w", len(p.answers[obj.key]), obj.key, err)
+		}
+		p.answers[obj.key] = append(p.answers[obj.key], entity)
+		streamed = append(streamed, StreamedEntity{QuestionKey: obj.key, Entity: entity})
+	}
+	return streamed, nil
+}
+
+// Finish implements [StreamParser].
+func (p *basicStreamParser) Finish() (map[string][]Entity, error) {
+	if !p.scanner.Complete() {
+		return nil, fmt.Errorf("response ended before the json was complete")
+	}
+	for _, key := range p.scanner.Keys() {
+		if _, ok := p.answers[key]; !ok {
+			p.answers[key] = []Entity{}
+		}
+	}
+	return p.answers, nil
+}
+
 // SystemPrompt implements [Protocol].
 func (qa *basicProtocol) SystemPrompt(qs map[string]Question) string {
 	builder := &mdBuilder{}
@@ -120,21 +238,28 @@ func (qa *basicProtocol) SystemPrompt(qs map[string]Question) string {
 	builder.Bulletf(0, "You can answer each question with some amount of answer objects")
 	builder.Bulletf(0, "Each type of answer object has a different purpose, with different properties")
 	builder.Bulletf(0, "Below are the allowed answer types")
-	for key, parser := range qa.types {
+	for _, key := range sortedKeys(qa.types) {
 		builder.Break(1)
 		builder.Headerf(2, "`%s`", key)
-		instructions := parser.Instructions()
+		instructions := qa.types[key].Instructions()
 		builder.Bulletf(0, "**%s**", instructions.OneLiner)
 		for _, d := range instructions.Details {
 			builder.Bullet(0, d)
 		}
 	}
+	for _, ext := range qa.extensions {
+		builder.Break(1)
+		for _, d := range ext.instructions() {
+			builder.Bullet(0, d)
+		}
+	}
 	builder.Break(2)
 
 	builder.Headerf(1, "Questions")
 	builder.Bulletf(0, "You should answer all questions")
-	builder.Bulletf(0, "If you cannot determine the answer to a question, return an empty list for that question")
-	for key, question := range qs {
+	builder.Bulletf(0, "If you cannot determine the answer to a question, return an empty list for that question, unless it says how many answers it needs")
+	for _, key := range sortedKeys(qs) {
+		question := qs[key]
 		builder.Break(1)
 		builder.Headerf(1, "`%s`", key)
 		builder.Bulletf(0, "**%s**", question.Question)
@@ -142,6 +267,9 @@ func (qa *basicProtocol) SystemPrompt(qs map[string]Question) string {
 			builder.Bullet(0, d)
 		}
 		builder.Bulletf(0, "Allowed response types: %s", strings.Join(question.AllowedTypeKeys, ", "))
+		if count := question.describeAnswerCount(); count != "" {
+			builder.Bulletf(0, "Number of answers: %s", count)
+		}
 	}
 
 	return builder.Build()
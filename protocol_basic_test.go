@@ -0,0 +1,102 @@
+package docqa
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBasicProtocolDeterministic(t *testing.T) {
+	newProtocol := func() Protocol {
+		types := map[string]Type{"text": testTextType{}, "amount": testAmountType{}, "name": testTextType{}, "label": testTextType{}}
+		return NewBasicProtocol(GetDefaultRoleAndTask(), types, WithEvidenceQuotes(), WithSelfRatedConfidence(), WithLineLocalisation())
+	}
+	newQuestions := func() map[string]Question {
+		return map[string]Question{
+			"title":   {Question: "What is the title?", AllowedTypeKeys: []string{"text", "name"}, MaxAnswers: 1},
+			"weights": {Question: "What weights are given?", AllowedTypeKeys: []string{"amount"}, MinAnswers: 1},
+			"authors": {Question: "Who wrote it?", Details: []string{"Full names"}, AllowedTypeKeys: []string{"name"}},
+			"labels":  {Question: "What labels are used?", AllowedTypeKeys: []string{"label", "text"}, MinAnswers: 2, MaxAnswers: 4},
+		}
+	}
+	render := func() (string, string) {
+		qa := newProtocol()
+		schema, err := json.Marshal(qa.Schema(newQuestions()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(schema), qa.SystemPrompt(newQuestions())
+	}
+	// Map iteration order is random, so any dependence on it shows up over enough runs.
+	wantSchema, wantPrompt := render()
+	for i := 0; i < 50; i++ {
+		schema, prompt := render()
+		if schema != wantSchema {
+			t.Fatalf("schema changed between runs:\n%s\n%s", wantSchema, schema)
+		}
+		if prompt != wantPrompt {
+			t.Fatalf("system prompt changed between runs:\n%s\n%s", wantPrompt, prompt)
+		}
+	}
+
+	// The questions and types appear in sorted order.
+	order := []string{"`amount`", "`label`", "`name`", "`text`", "`authors`", "`labels`", "`title`", "`weights`"}
+	last := -1
+	for _, heading := range order {
+		i := strings.Index(wantPrompt, "# "+heading)
+		if i < last {
+			t.Errorf("expected %s to come after the headings before it", heading)
+		}
+		last = i
+	}
+}
+
+func TestBasicProtocolCardinality(t *testing.T) {
+	questions := map[string]Question{
+		"any":   {Question: "Q?", AllowedTypeKeys: []string{"text"}},
+		"one":   {Question: "Q?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1, MaxAnswers: 1},
+		"some":  {Question: "Q?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1, MaxAnswers: 3},
+		"most":  {Question: "Q?", AllowedTypeKeys: []string{"text"}, MaxAnswers: 2},
+		"least": {Question: "Q?", AllowedTypeKeys: []string{"text"}, MinAnswers: 2},
+	}
+	cases := []struct {
+		key      string
+		minItems any
+		maxItems any
+		prompt   string
+	}{
+		{key: "any"},
+		{key: "one", minItems: 1, maxItems: 1, prompt: "exactly 1"},
+		{key: "some", minItems: 1, maxItems: 3, prompt: "between 1 and 3"},
+		{key: "most", maxItems: 2, prompt: "at most 2"},
+		{key: "least", minItems: 2, prompt: "at least 2"},
+	}
+	schema := newTestTextProtocol().Schema(questions)
+	omitted := newTestTextProtocol(WithoutCardinalitySchema()).Schema(questions)
+	prompt := newTestTextProtocol(WithoutCardinalitySchema()).SystemPrompt(questions)
+	for _, tc := range cases {
+		property := jsonPath(t, schema, "properties", tc.key).(map[string]any)
+		if property["minItems"] != tc.minItems || property["maxItems"] != tc.maxItems {
+			t.Errorf("%s: expected minItems %v and maxItems %v, got %v and %v", tc.key, tc.minItems, tc.maxItems, property["minItems"], property["maxItems"])
+		}
+		property = jsonPath(t, omitted, "properties", tc.key).(map[string]any)
+		if _, ok := property["minItems"]; ok {
+			t.Errorf("%s: expected minItems to be omitted", tc.key)
+		}
+		if _, ok := property["maxItems"]; ok {
+			t.Errorf("%s: expected maxItems to be omitted", tc.key)
+		}
+		// The prompt still states the limits when they are left out of the schema.
+		section := prompt[strings.Index(prompt, "# `"+tc.key+"`"):]
+		if next := strings.Index(section[1:], "# `"); next >= 0 {
+			section = section[:next+1]
+		}
+		hasCount := strings.Contains(section, "Number of answers:")
+		if tc.prompt == "" && hasCount {
+			t.Errorf("%s: expected no answer count in the prompt, got %q", tc.key, section)
+		}
+		if tc.prompt != "" && !strings.Contains(section, "Number of answers: "+tc.prompt) {
+			t.Errorf("%s: expected %q in the prompt, got %q", tc.key, tc.prompt, section)
+		}
+	}
+}
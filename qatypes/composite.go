@@ -0,0 +1,142 @@
+package qatypes
+
+import (
+	"fmt"
+
+	"github.com/JoshPattman/docqa"
+)
+
+// CompositeField is a single named field of a [CompositeType], whose value is parsed by another type.
+type CompositeField struct {
+	// Name is the json property name of the field, for example `signing_date`.
+	Name string
+	// Description tells the LLM what the field holds, for example `The date this party signed the contract`.
+	Description string
+	// Type parses the value of the field.
+	Type EntityCreator
+}
+
+// CompositeEntity is an entity made of several related entities, one for each field of its [CompositeType].
+type CompositeEntity struct {
+	docqa.EntityAttributes
+	// Fields holds the entity of each field, keyed by field name.
+	Fields map[string]docqa.Entity
+	key    string
+	fields []CompositeField
+}
+
+// EntityKey implements [docqa.KeyedEntity].
+func (e *CompositeEntity) EntityKey() string {
+	return e.key
+}
+
+// MakeContent implements [docqa.Entity].
+func (e *CompositeEntity) MakeContent() (map[string]any, error) {
+	content := make(map[string]any, len(e.fields))
+	for _, field := range e.fields {
+		fieldEntity, ok := e.Fields[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("composite %s is missing field %s", e.key, field.Name)
+		}
+		fieldContent, err := fieldEntity.MakeContent()
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		content[field.Name] = fieldContent
+	}
+	return content, nil
+}
+
+// LoadContent implements [docqa.Entity].
+func (e *CompositeEntity) LoadContent(dict map[string]any) error {
+	e.Fields = make(map[string]docqa.Entity, len(e.fields))
+	for _, field := range e.fields {
+		fieldContent, err := get[map[string]any](dict, field.Name)
+		if err != nil {
+			return err
+		}
+		fieldEntity := field.Type.NewEntity()
+		if err := fieldEntity.LoadContent(fieldContent); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		e.Fields[field.Name] = fieldEntity
+	}
+	return nil
+}
+
+// CompositeType defines a type to create [CompositeEntity], for records whose fields belong together,
+// such as each party to a contract with their name, role, and signing date.
+type CompositeType struct {
+	key      string
+	oneLiner string
+	fields   []CompositeField
+}
+
+// NewCompositeType creates a [CompositeType] made of the given fields.
+// The key must be the same as the type key it is given to the protocol (and [docqa.EntityJsoner]) with,
+// as every composite shares the same entity type.
+func NewCompositeType(key string, oneLiner string, fields ...CompositeField) *CompositeType {
+	return &CompositeType{
+		key:      key,
+		oneLiner: oneLiner,
+		fields:   fields,
+	}
+}
+
+// NewEntity implements [EntityCreator].
+func (t *CompositeType) NewEntity() docqa.Entity {
+	return &CompositeEntity{
+		key:    t.key,
+		fields: t.fields,
+	}
+}
+
+// Parse implements [docqa.Type].
+func (t *CompositeType) Parse(value map[string]any) (docqa.Entity, error) {
+	e := t.NewEntity().(*CompositeEntity)
+	e.Fields = make(map[string]docqa.Entity, len(t.fields))
+	for _, field := range t.fields {
+		fieldValue, err := get[map[string]any](value, field.Name)
+		if err != nil {
+			return nil, err
+		}
+		fieldEntity, err := field.Type.Parse(fieldValue)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		e.Fields[field.Name] = fieldEntity
+	}
+	return e, nil
+}
+
+// SchemaProperties implements [docqa.Type].
+func (t *CompositeType) SchemaProperties() map[string]any {
+	properties := make(map[string]any, len(t.fields))
+	for _, field := range t.fields {
+		fieldProps := field.Type.SchemaProperties()
+		properties[field.Name] = map[string]any{
+			"type":                 "object",
+			"description":          field.Description,
+			"properties":           fieldProps,
+			"required":             sortedPropertyNames(fieldProps),
+			"additionalProperties": false,
+		}
+	}
+	return properties
+}
+
+// Instructions implements [docqa.Type].
+func (t *CompositeType) Instructions() docqa.TypeInstructions {
+	details := make([]string, 0)
+	for _, field := range t.fields {
+		fieldInstructions := field.Type.Instructions()
+		details = append(details, fmt.Sprintf("`%s`: %s (%s)", field.Name, field.Description, fieldInstructions.OneLiner))
+		for _, d := range fieldInstructions.Details {
+			details = append(details, fmt.Sprintf("For `%s`: %s", field.Name, d))
+		}
+	}
+	return docqa.TypeInstructions{
+		OneLiner: t.oneLiner,
+		Details:  details,
+	}
+}
@@ -41,6 +41,11 @@ func NewDateType() *DateType {
 	return &DateType{}
 }
 
+// NewEntity implements [EntityCreator].
+func (p *DateType) NewEntity() docqa.Entity {
+	return &DateEntity{}
+}
+
 // Parse implements [docqa.Type].
 func (p *DateType) Parse(value map[string]any) (docqa.Entity, error) {
 	var year, month, day float64
@@ -2,6 +2,14 @@ package qatypes
 
 import "github.com/JoshPattman/docqa"
 
+// EntityCreator is a [docqa.Type] that can also create an empty [docqa.Entity] of the kind it parses into.
+// Every type in this package is one.
+type EntityCreator interface {
+	docqa.Type
+	// NewEntity creates an empty entity, ready for [docqa.Entity.LoadContent].
+	NewEntity() docqa.Entity
+}
+
 // GetDefaultTypes returns a list of all the [docqa.Type] in this package,
 // keyed by type key.
 func GetDefaultTypes() map[string]docqa.Type {
@@ -16,8 +24,8 @@ func GetDefaultTypes() map[string]docqa.Type {
 // keyed by entity key.
 func GetDefaultFactories() map[string]func() docqa.Entity {
 	return map[string]func() docqa.Entity{
-		"name": func() docqa.Entity { return &NameEntity{} },
-		"date": func() docqa.Entity { return &DateEntity{} },
-		"text": func() docqa.Entity { return &TextEntity{} },
+		"name": NewNameType().NewEntity,
+		"date": NewDateType().NewEntity,
+		"text": NewTextType().NewEntity,
 	}
 }
@@ -41,6 +41,11 @@ func NewNameType() *NameType {
 	return &NameType{}
 }
 
+// NewEntity implements [EntityCreator].
+func (p *NameType) NewEntity() docqa.Entity {
+	return &NameEntity{}
+}
+
 // Parse implements [docqa.Type].
 func (p *NameType) Parse(value map[string]any) (docqa.Entity, error) {
 	e := &NameEntity{}
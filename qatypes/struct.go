@@ -0,0 +1,239 @@
+package qatypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JoshPattman/docqa"
+)
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// StructEntity is an entity holding a value of a Go struct, created by a [StructType].
+type StructEntity[T any] struct {
+	docqa.EntityAttributes
+	Value T
+}
+
+// MakeContent implements [docqa.Entity].
+func (e *StructEntity[T]) MakeContent() (map[string]any, error) {
+	bs, err := json.Marshal(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	content := make(map[string]any)
+	if err := json.Unmarshal(bs, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// LoadContent implements [docqa.Entity].
+func (e *StructEntity[T]) LoadContent(dict map[string]any) error {
+	bs, err := json.Marshal(dict)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, &e.Value)
+}
+
+// StructType defines a type to create [StructEntity], deriving its schema and instructions from the fields of T.
+//
+// Every exported field of T becomes a required property, named by its `json` tag (or field name), and may also have:
+//   - a `desc` tag, describing the field to the LLM.
+//   - an `enum` tag, a comma-separated list of the allowed values of a string (or list of strings) field.
+//
+// Fields may be strings, bools, numbers, [time.Time]s (as RFC 3339 strings), nested structs, or slices of those.
+// Pointers, maps, interfaces, embedded structs, recursive structs, and other types with custom json decoding are not supported.
+type StructType[T any] struct {
+	oneLiner   string
+	details    []string
+	properties map[string]any
+	fieldDocs  []string
+}
+
+// NewStructType creates a [StructType] for T, returning an error if T is not a struct made of supported fields.
+// The oneLiner and details are the [docqa.TypeInstructions] for the type as a whole, and are followed by a description of each field.
+func NewStructType[T any](oneLiner string, details ...string) (*StructType[T], error) {
+	t := &StructType[T]{
+		oneLiner: oneLiner,
+		details:  details,
+	}
+	rt := reflect.TypeFor[T]()
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("struct type must be created from a struct, not %s", rt)
+	}
+	properties, err := t.structProperties(rt, "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create struct type from %s: %w", rt, err)
+	}
+	if _, ok := properties["answer_type"]; ok {
+		return nil, fmt.Errorf("cannot create struct type from %s: answer_type is a reserved property name", rt)
+	}
+	t.properties = properties
+	return t, nil
+}
+
+// structProperties builds the schema properties of a struct, recording the documentation of each field as it goes.
+// Visiting lists the structs that are currently being expanded, so that recursive structs cause an error.
+func (t *StructType[T]) structProperties(rt reflect.Type, prefix string, visiting []reflect.Type) (map[string]any, error) {
+	if slices.Contains(visiting, rt) {
+		return nil, fmt.Errorf("%s refers to itself, which is not supported", rt)
+	}
+	visiting = append(visiting, rt)
+	properties := make(map[string]any)
+	for _, field := range reflect.VisibleFields(rt) {
+		if !field.IsExported() || len(field.Index) > 1 {
+			continue
+		}
+		if field.Anonymous {
+			return nil, fmt.Errorf("embedded field %s is not supported", field.Name)
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		path := prefix + name
+		var enum []string
+		if tag := field.Tag.Get("enum"); tag != "" {
+			for _, v := range strings.Split(tag, ",") {
+				enum = append(enum, strings.TrimSpace(v))
+			}
+		}
+		prop, err := t.fieldSchema(field.Type, path, enum, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		desc := field.Tag.Get("desc")
+		if desc != "" {
+			prop["description"] = desc
+		}
+		isTime := field.Type == reflect.TypeFor[time.Time]()
+		if desc != "" || len(enum) > 0 || isTime {
+			doc := fmt.Sprintf("`%s`", path)
+			if desc != "" {
+				doc += ": " + desc
+			}
+			if len(enum) > 0 {
+				doc += fmt.Sprintf(" (one of %s)", strings.Join(enum, ", "))
+			}
+			if isTime {
+				doc += " (an RFC 3339 date-time, such as 2006-01-02T15:04:05Z)"
+			}
+			t.fieldDocs = append(t.fieldDocs, doc)
+		}
+		properties[name] = prop
+	}
+	return properties, nil
+}
+
+// fieldSchema builds the schema of a single field of type rt.
+func (t *StructType[T]) fieldSchema(rt reflect.Type, path string, enum []string, visiting []reflect.Type) (map[string]any, error) {
+	if len(enum) > 0 && rt.Kind() != reflect.String && !(rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.String) {
+		return nil, fmt.Errorf("enum tag is only supported on strings, not %s", rt)
+	}
+	if rt == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	}
+	// The schema of a type that decodes itself cannot be derived from its fields.
+	if rt.Implements(jsonUnmarshalerType) || reflect.PointerTo(rt).Implements(jsonUnmarshalerType) {
+		return nil, fmt.Errorf("%s has custom json decoding, which is not supported", rt)
+	}
+	switch rt.Kind() {
+	case reflect.String:
+		if len(enum) > 0 {
+			return map[string]any{"type": "string", "enum": enum}, nil
+		}
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice, reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return nil, fmt.Errorf("byte slices are not supported")
+		}
+		items, err := t.fieldSchema(rt.Elem(), path+"[]", enum, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Struct:
+		properties, err := t.structProperties(rt, path+".", visiting)
+		if err != nil {
+			return nil, err
+		}
+		if len(properties) == 0 {
+			return nil, fmt.Errorf("%s has no exported fields", rt)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             sortedPropertyNames(properties),
+			"additionalProperties": false,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%s is not supported", rt)
+	}
+}
+
+// NewEntity implements [EntityCreator].
+func (t *StructType[T]) NewEntity() docqa.Entity {
+	return &StructEntity[T]{}
+}
+
+// Parse implements [docqa.Type].
+func (t *StructType[T]) Parse(value map[string]any) (docqa.Entity, error) {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	// Extra properties, such as the answer_type, are allowed as they are not part of the struct.
+	schema := map[string]any{
+		"type":       "object",
+		"properties": t.properties,
+		"required":   sortedPropertyNames(t.properties),
+	}
+	if err := docqa.ValidateJSON(string(bs), schema); err != nil {
+		return nil, err
+	}
+	e := &StructEntity[T]{}
+	if err := json.Unmarshal(bs, &e.Value); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SchemaProperties implements [docqa.Type].
+func (t *StructType[T]) SchemaProperties() map[string]any {
+	// The protocol may add to the returned map, so it must not share the top level with t.
+	properties := make(map[string]any, len(t.properties))
+	for k, v := range t.properties {
+		properties[k] = v
+	}
+	return properties
+}
+
+// Instructions implements [docqa.Type].
+func (t *StructType[T]) Instructions() docqa.TypeInstructions {
+	details := make([]string, 0, len(t.details)+len(t.fieldDocs))
+	details = append(details, t.details...)
+	details = append(details, t.fieldDocs...)
+	return docqa.TypeInstructions{
+		OneLiner: t.oneLiner,
+		Details:  details,
+	}
+}
@@ -0,0 +1,211 @@
+package qatypes
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoshPattman/docqa"
+)
+
+type testParty struct {
+	Name    string   `json:"name" desc:"The full legal name"`
+	Role    string   `json:"role" enum:"buyer, seller"`
+	Tags    []string `json:"tags" enum:"new,returning"`
+	Ignored string   `json:"-"`
+	private string
+}
+
+type testContract struct {
+	Title   string      `desc:"The title of the contract"`
+	Signed  time.Time   `json:"signed"`
+	Value   float64     `json:"value"`
+	Pages   int         `json:"pages"`
+	Binding bool        `json:"binding"`
+	Parties []testParty `json:"parties"`
+}
+
+func TestNewStructTypeSchema(t *testing.T) {
+	st, err := NewStructType[testContract]("A contract.", "Only include signed contracts.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	party := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string", "description": "The full legal name"},
+			"role": map[string]any{"type": "string", "enum": []string{"buyer", "seller"}},
+			"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": []string{"new", "returning"}}},
+		},
+		"required":             []string{"name", "role", "tags"},
+		"additionalProperties": false,
+	}
+	expected := map[string]any{
+		"Title":   map[string]any{"type": "string", "description": "The title of the contract"},
+		"signed":  map[string]any{"type": "string", "format": "date-time"},
+		"value":   map[string]any{"type": "number"},
+		"pages":   map[string]any{"type": "integer"},
+		"binding": map[string]any{"type": "boolean"},
+		"parties": map[string]any{"type": "array", "items": party},
+	}
+	if got := st.SchemaProperties(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected schema properties\n%v\ngot\n%v", expected, got)
+	}
+
+	instructions := st.Instructions()
+	if instructions.OneLiner != "A contract." {
+		t.Errorf("unexpected one liner %q", instructions.OneLiner)
+	}
+	expectedDetails := []string{
+		"Only include signed contracts.",
+		"`Title`: The title of the contract",
+		"`signed` (an RFC 3339 date-time, such as 2006-01-02T15:04:05Z)",
+		"`parties[].name`: The full legal name",
+		"`parties[].role` (one of buyer, seller)",
+		"`parties[].tags` (one of new, returning)",
+	}
+	if !reflect.DeepEqual(instructions.Details, expectedDetails) {
+		t.Errorf("expected details\n%s\ngot\n%s", strings.Join(expectedDetails, "\n"), strings.Join(instructions.Details, "\n"))
+	}
+}
+
+func TestStructTypeParse(t *testing.T) {
+	st, err := NewStructType[testContract]("A contract.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := `{
+		"answer_type": "contract", "Title": "Lease", "signed": "2024-03-01T09:30:00Z", "value": 1200.5, "pages": 12, "binding": true,
+		"parties": [{"name": "Acme Ltd", "role": "seller", "tags": ["returning"]}]
+	}`
+	var value map[string]any
+	if err := json.Unmarshal([]byte(valid), &value); err != nil {
+		t.Fatal(err)
+	}
+	entity, err := st.Parse(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := entity.(*StructEntity[testContract]).Value
+	want := testContract{
+		Title:   "Lease",
+		Signed:  time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+		Value:   1200.5,
+		Pages:   12,
+		Binding: true,
+		Parties: []testParty{{Name: "Acme Ltd", Role: "seller", Tags: []string{"returning"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	// The content must round trip through a fresh entity.
+	content, err := entity.MakeContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := st.NewEntity()
+	if err := loaded.LoadContent(content); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.(*StructEntity[testContract]).Value, want) {
+		t.Errorf("content did not round trip, got %+v", loaded.(*StructEntity[testContract]).Value)
+	}
+
+	invalid := []struct {
+		name  string
+		field string
+		value any
+	}{
+		{"bad enum", "parties", []any{map[string]any{"name": "Acme", "role": "broker", "tags": []any{}}}},
+		{"bad date", "signed", "1 March 2024"},
+		{"wrong type", "pages", "twelve"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			bad := make(map[string]any, len(value))
+			for k, v := range value {
+				bad[k] = v
+			}
+			bad[tc.field] = tc.value
+			var validationErr *docqa.SchemaValidationError
+			if _, err := st.Parse(bad); err == nil || !strings.Contains(err.Error(), tc.field) || !errors.As(err, &validationErr) {
+				t.Errorf("expected a schema validation error about %s, got %v", tc.field, err)
+			}
+		})
+	}
+}
+
+type testRecursive struct {
+	Name     string          `json:"name"`
+	Children []testRecursive `json:"children"`
+}
+
+type testIndirectA struct {
+	B []testIndirectB
+}
+
+type testIndirectB struct {
+	A testIndirectA
+}
+
+type testSibling struct {
+	Name string
+}
+
+type testSiblings struct {
+	First  testSibling
+	Second testSibling
+	Many   []testSibling
+}
+
+type testUnmarshaler struct{}
+
+func (*testUnmarshaler) UnmarshalJSON([]byte) error { return nil }
+
+func TestNewStructTypeErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		make func() error
+		err  string
+	}{
+		{"not a struct", newStructTypeErr[string], "not string"},
+		{"recursive", newStructTypeErr[testRecursive], "refers to itself"},
+		{"indirectly recursive", newStructTypeErr[testIndirectA], "refers to itself"},
+		{"pointer", newStructTypeErr[struct{ P *string }], "*string is not supported"},
+		{"map", newStructTypeErr[struct{ M map[string]string }], "is not supported"},
+		{"interface", newStructTypeErr[struct{ I any }], "is not supported"},
+		{"byte slice", newStructTypeErr[struct{ B []byte }], "byte slices are not supported"},
+		{"custom json decoding", newStructTypeErr[struct{ U testUnmarshaler }], "custom json decoding"},
+		{"embedded", newStructTypeErr[struct{ docqa.Range }], "embedded field"},
+		{"no exported fields", newStructTypeErr[struct{ S struct{ x int } }], "no exported fields"},
+		{"enum on a number", newStructTypeErr[struct {
+			N int `enum:"1,2"`
+		}], "enum tag is only supported on strings"},
+		{"reserved name", newStructTypeErr[struct {
+			T string `json:"answer_type"`
+		}], "answer_type is a reserved property name"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.make()
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	t.Run("repeated struct that is not recursive", func(t *testing.T) {
+		if err := newStructTypeErr[testSiblings](); err != nil {
+			t.Errorf("expected a struct used by several fields to be allowed, got %v", err)
+		}
+	})
+}
+
+func newStructTypeErr[T any]() error {
+	_, err := NewStructType[T]("")
+	return err
+}
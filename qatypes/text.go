@@ -35,6 +35,11 @@ func NewTextType() *TextType {
 	return &TextType{}
 }
 
+// NewEntity implements [EntityCreator].
+func (t *TextType) NewEntity() docqa.Entity {
+	return &TextEntity{}
+}
+
 // Parse implements [docqa.Type].
 func (t *TextType) Parse(value map[string]any) (docqa.Entity, error) {
 	e := &TextEntity{}
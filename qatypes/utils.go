@@ -2,6 +2,7 @@ package qatypes
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -20,3 +21,13 @@ func get[T any](m map[string]any, k string) (T, error) {
 		}
 	}
 }
+
+// sortedPropertyNames lists the names of schema properties in a deterministic order, for use as `required`.
+func sortedPropertyNames(properties map[string]any) []string {
+	names := make([]string, 0, len(properties))
+	for k := range properties {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -1,5 +1,10 @@
 package docqa
 
+import (
+	"errors"
+	"fmt"
+)
+
 // Question defines a sepcific question to send to the LLM.
 type Question struct {
 	// Question is the one-liner, for example `Who is the author of this document?`.
@@ -8,4 +13,64 @@ type Question struct {
 	Details []string `json:"details"`
 	// AllowedTypeKeys lists all the keys of the types which the LLM may respond with.
 	AllowedTypeKeys []string `json:"allowed_type_keys"`
+	// MinAnswers is the fewest answers the LLM may respond with. Zero allows the question to go unanswered.
+	// It must not be more than MaxAnswers, unless MaxAnswers is zero.
+	MinAnswers int `json:"min_answers,omitempty"`
+	// MaxAnswers is the most answers the LLM may respond with. Zero means there is no limit.
+	MaxAnswers int `json:"max_answers,omitempty"`
+}
+
+// describeAnswerCount describes how many answers the question needs, such as `exactly 1`,
+// or returns an empty string if any number of answers is allowed.
+func (q Question) describeAnswerCount() string {
+	switch {
+	case q.MaxAnswers > 0 && q.MinAnswers == q.MaxAnswers:
+		return fmt.Sprintf("exactly %d", q.MaxAnswers)
+	case q.MaxAnswers > 0 && q.MinAnswers > 0:
+		return fmt.Sprintf("between %d and %d", q.MinAnswers, q.MaxAnswers)
+	case q.MaxAnswers > 0:
+		return fmt.Sprintf("at most %d", q.MaxAnswers)
+	case q.MinAnswers > 0:
+		return fmt.Sprintf("at least %d", q.MinAnswers)
+	default:
+		return ""
+	}
+}
+
+// validateQuestions checks that every question can be answered, before any calls are made.
+// A question whose [Question.MinAnswers] is more than its [Question.MaxAnswers] can never be satisfied.
+func validateQuestions(questions map[string]Question) error {
+	var errs []error
+	for _, key := range sortedKeys(questions) {
+		q := questions[key]
+		if q.MaxAnswers > 0 && q.MinAnswers > q.MaxAnswers {
+			errs = append(errs, fmt.Errorf("question %q has MinAnswers %d, which is more than its MaxAnswers %d", key, q.MinAnswers, q.MaxAnswers))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateCardinality checks that the number of answers to each question is within its
+// [Question.MinAnswers] and [Question.MaxAnswers], returning an error describing every question that is not.
+// The extraction functions in this package call it after parsing, as [Protocol.ParseResponse] does not see the questions.
+func ValidateCardinality(questions map[string]Question, answers map[string][]Entity) error {
+	var errs []error
+	for _, key := range sortedKeys(questions) {
+		q, n := questions[key], len(answers[key])
+		if n < q.MinAnswers || (q.MaxAnswers > 0 && n > q.MaxAnswers) {
+			errs = append(errs, fmt.Errorf("question %q has %d answers, but needs %s", key, n, q.describeAnswerCount()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// trimToMaxAnswers keeps only the first [Question.MaxAnswers] answers to each question.
+// It is used when the answers of several calls are combined, as each call may have picked different answers,
+// so the answers must already be ordered best first.
+func trimToMaxAnswers(questions map[string]Question, answers map[string][]Entity) {
+	for key, q := range questions {
+		if q.MaxAnswers > 0 && len(answers[key]) > q.MaxAnswers {
+			answers[key] = answers[key][:q.MaxAnswers]
+		}
+	}
 }
@@ -0,0 +1,126 @@
+package docqa
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDescribeAnswerCount(t *testing.T) {
+	cases := []struct {
+		min, max int
+		want     string
+	}{
+		{0, 0, ""},
+		{1, 1, "exactly 1"},
+		{3, 3, "exactly 3"},
+		{1, 3, "between 1 and 3"},
+		{0, 2, "at most 2"},
+		{2, 0, "at least 2"},
+	}
+	for _, tc := range cases {
+		q := Question{MinAnswers: tc.min, MaxAnswers: tc.max}
+		if got := q.describeAnswerCount(); got != tc.want {
+			t.Errorf("min %d, max %d: expected %q, got %q", tc.min, tc.max, tc.want, got)
+		}
+	}
+}
+
+func TestValidateCardinality(t *testing.T) {
+	questions := map[string]Question{
+		"any":     {},
+		"one":     {MinAnswers: 1, MaxAnswers: 1},
+		"some":    {MinAnswers: 1, MaxAnswers: 2},
+		"atLeast": {MinAnswers: 2},
+	}
+	cases := []struct {
+		name    string
+		answers map[string][]Entity
+		// invalid lists the questions that should be reported.
+		invalid []string
+	}{
+		{
+			name:    "all valid",
+			answers: map[string][]Entity{"one": textSample("A")["q"], "some": textSample("A", "B")["q"], "atLeast": textSample("A", "B", "C")["q"]},
+		},
+		{
+			name:    "missing answers count as none",
+			answers: map[string][]Entity{},
+			invalid: []string{"atLeast", "one", "some"},
+		},
+		{
+			name:    "too many",
+			answers: map[string][]Entity{"any": textSample("A", "B", "C")["q"], "one": textSample("A", "B")["q"], "some": textSample("A", "B", "C")["q"], "atLeast": textSample("A", "B")["q"]},
+			invalid: []string{"one", "some"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCardinality(questions, tc.answers)
+			if len(tc.invalid) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected %v to be invalid", tc.invalid)
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tc.invalid) {
+				t.Fatalf("expected %d errors, got %q", len(tc.invalid), err)
+			}
+			for i, key := range tc.invalid {
+				if !strings.HasPrefix(lines[i], "question \""+key+"\"") {
+					t.Errorf("expected error %d to be about %s, got %q", i, key, lines[i])
+				}
+			}
+		})
+	}
+
+	err := ValidateCardinality(map[string]Question{"q": {MinAnswers: 1, MaxAnswers: 2}}, map[string][]Entity{})
+	if err == nil || err.Error() != `question "q" has 0 answers, but needs between 1 and 2` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestImpossibleQuestionsRejected(t *testing.T) {
+	questions := map[string]Question{
+		"fine":       {Question: "Q?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1, MaxAnswers: 1},
+		"impossible": {Question: "Q?", AllowedTypeKeys: []string{"text"}, MinAnswers: 3, MaxAnswers: 1},
+	}
+	client := stubResponses(LLMUsage{}, `{"fine":[],"impossible":[]}`)
+	qa := newTestTextProtocol()
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"ExtractAnswersContext": func() error {
+			_, _, err := ExtractAnswersContext(ctx, client, qa, questions, "doc")
+			return err
+		},
+		"ExtractAnswersChunked": func() error {
+			_, _, err := ExtractAnswersChunked(ctx, client, qa, questions, "doc", 100, 0)
+			return err
+		},
+		"ExtractAnswersEnsemble": func() error {
+			_, _, err := ExtractAnswersEnsemble(ctx, []Client{client}, qa, questions, "doc", 0)
+			return err
+		},
+		"ExtractAnswersRetrieved": func() error {
+			_, _, err := ExtractAnswersRetrieved(ctx, client, qa, questions, "doc", 100, 1)
+			return err
+		},
+		"WriteRequests": func() error {
+			var out strings.Builder
+			return NewOpenAIBatcher("key", "model").WriteRequests(&out, []BatchRequest{{ID: "a", Protocol: qa, Questions: questions, Document: "doc"}})
+		},
+	}
+	for _, name := range sortedKeys(calls) {
+		err := calls[name]()
+		if err == nil || !strings.Contains(err.Error(), `question "impossible" has MinAnswers 3, which is more than its MaxAnswers 1`) {
+			t.Errorf("%s: expected the impossible question to be rejected, got %v", name, err)
+		}
+	}
+	if len(client.calls) != 0 {
+		t.Errorf("expected no calls to be made, got %d", len(client.calls))
+	}
+}
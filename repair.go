@@ -0,0 +1,26 @@
+package docqa
+
+import (
+	"errors"
+	"strings"
+)
+
+// repairPrompt builds the user message asking the model to correct its previous response.
+// Problems with the structure of the response are found with [ValidateJSON], as they are more precise,
+// falling back to the parse error if the response matched the schema.
+func repairPrompt(resp string, schema map[string]any, parseErr error) string {
+	problems := []string{parseErr.Error()}
+	var validationErr *SchemaValidationError
+	if errors.As(ValidateJSON(resp, schema), &validationErr) {
+		problems = validationErr.Problems
+	}
+	builder := &mdBuilder{}
+	builder.Headerf(1, "Invalid Response")
+	builder.Bulletf(0, "Your previous response could not be used, due to these problems")
+	for _, problem := range problems {
+		builder.Bullet(1, strings.TrimSpace(problem))
+	}
+	builder.Bulletf(0, "Respond again with the complete corrected response, not just the parts that changed")
+	builder.Bulletf(0, "Follow the schema and the instructions from the system prompt exactly")
+	return builder.Build()
+}
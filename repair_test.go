@@ -0,0 +1,184 @@
+package docqa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtractAnswersRepair(t *testing.T) {
+	const (
+		good      = `{"title": [{"answer_type": "text", "text": "Annual Report"}]}`
+		wrongType = `{"title": [{"answer_type": "number", "text": "Annual Report"}]}`
+		noAnswers = `{"title": []}`
+		notJSON   = `The title is Annual Report.`
+	)
+	usage := LLMUsage{InputTokens: 100, OutputTokens: 10}
+	questions := map[string]Question{
+		"title": {Question: "What is the title?", AllowedTypeKeys: []string{"text"}, MinAnswers: 1, MaxAnswers: 1},
+	}
+	cases := []struct {
+		name      string
+		responses []string
+		attempts  int
+		calls     int
+		// problem is a substring of the repair prompt sent after the first response.
+		problem string
+		success bool
+	}{
+		{name: "no repair by default", responses: []string{wrongType, good}, attempts: 0, calls: 1},
+		{name: "unknown answer type", responses: []string{wrongType, good}, attempts: 1, calls: 2, problem: "$.title[0].answer_type: expected text but got number", success: true},
+		{name: "not json", responses: []string{notJSON, good}, attempts: 1, calls: 2, problem: "response was not valid json", success: true},
+		{name: "too few answers", responses: []string{noAnswers, good}, attempts: 1, calls: 2, problem: "$.title: expected at least 1 items but got 0", success: true},
+		{name: "attempts run out", responses: []string{wrongType, notJSON, good}, attempts: 1, calls: 2, problem: "answer_type"},
+		{name: "unused attempts", responses: []string{good}, attempts: 3, calls: 1, success: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := stubResponses(usage, tc.responses...)
+			answers, gotUsage, err := ExtractAnswersContext(context.Background(), client, newTestTextProtocol(), questions, "Annual Report", WithRepairAttempts(tc.attempts))
+			if len(client.calls) != tc.calls {
+				t.Fatalf("expected %d calls, got %d", tc.calls, len(client.calls))
+			}
+			if want := (LLMUsage{InputTokens: 100 * tc.calls, OutputTokens: 10 * tc.calls}); gotUsage != want {
+				t.Errorf("expected usage summed over every turn %+v, got %+v", want, gotUsage)
+			}
+			if tc.success {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(answers["title"]) != 1 || answers["title"][0].(*testTextEntity).Text != "Annual Report" {
+					t.Errorf("unexpected answers %v", answers)
+				}
+			} else {
+				var malformed *MalformedResponseError
+				if !errors.As(err, &malformed) {
+					t.Errorf("expected a MalformedResponseError, got %v", err)
+				}
+			}
+			if tc.calls < 2 {
+				return
+			}
+			// The second turn continues the conversation with the bad response and what was wrong with it.
+			conversation := client.calls[1]
+			if len(conversation) != 3 {
+				t.Fatalf("expected the repair turn to send 3 messages, got %d", len(conversation))
+			}
+			if conversation[0].Role != UserRole || conversation[0].Content != "Annual Report" {
+				t.Errorf("expected the document as the first message, got %+v", conversation[0])
+			}
+			if conversation[1].Role != AssistantRole || conversation[1].Content != tc.responses[0] {
+				t.Errorf("expected the bad response as the second message, got %+v", conversation[1])
+			}
+			if conversation[2].Role != UserRole || !strings.Contains(conversation[2].Content, tc.problem) {
+				t.Errorf("expected the repair prompt to mention %q, got\n%s", tc.problem, conversation[2].Content)
+			}
+		})
+	}
+}
+
+func TestExtractAnswersRepairClientErrors(t *testing.T) {
+	usage := LLMUsage{InputTokens: 100, OutputTokens: 10}
+	partial := PartialResponse{Content: `{"title": [`, Usage: usage}
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"refusal", &RefusalError{PartialResponse: partial, Refusal: "I cannot help with that."}},
+		{"truncated", &TruncatedError{PartialResponse: partial}},
+		{"content filter", &ContentFilterError{PartialResponse: partial}},
+		{"malformed client response", newMalformedResponseError("", usage, errors.New("response had no content"))},
+		{"status error", &HTTPStatusError{StatusCode: 400}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &stubClient{respond: func(call int, _ string, _ []Message) (string, LLMUsage, error) {
+				if call > 0 {
+					return `{"title": []}`, usage, nil
+				}
+				return "", usage, tc.err
+			}}
+			_, gotUsage, err := ExtractAnswersContext(context.Background(), client, newTestTextProtocol(), map[string]Question{"title": {}}, "doc", WithRepairAttempts(2))
+			if len(client.calls) != 1 {
+				t.Errorf("expected errors from the client not to be repaired, got %d calls", len(client.calls))
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected the client's error, got %v", err)
+			}
+			if gotUsage != usage {
+				t.Errorf("expected the usage of the failed call, got %+v", gotUsage)
+			}
+		})
+	}
+}
+
+func TestExtractAnswersRepairWithoutConversationClient(t *testing.T) {
+	var prompts []string
+	client := singlePromptClient(func(userPrompt string) (string, LLMUsage, error) {
+		prompts = append(prompts, userPrompt)
+		if len(prompts) == 1 {
+			return `{"title": [{"answer_type": "number"}]}`, LLMUsage{}, nil
+		}
+		return `{"title": [{"answer_type": "text", "text": "Annual Report"}]}`, LLMUsage{}, nil
+	})
+	_, _, err := ExtractAnswersContext(context.Background(), client, newTestTextProtocol(), map[string]Question{"title": {}}, "Annual Report", WithRepairAttempts(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(prompts))
+	}
+	for _, want := range []string{"### user\nAnnual Report", "### assistant\n{\"title\"", "Invalid Response"} {
+		if !strings.Contains(prompts[1], want) {
+			t.Errorf("expected the flattened conversation to contain %q, got\n%s", want, prompts[1])
+		}
+	}
+}
+
+// singlePromptClient is a [Client] that cannot continue a conversation.
+type singlePromptClient func(userPrompt string) (string, LLMUsage, error)
+
+func (f singlePromptClient) GetLLMResponse(systemPrompt string, userPrompt string, schema map[string]any) (string, LLMUsage, error) {
+	return f(userPrompt)
+}
+
+func TestRepairPrompt(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"a": map[string]any{"type": "integer"}},
+		"required":   []any{"a", "b"},
+	}
+	cases := []struct {
+		name     string
+		resp     string
+		parseErr error
+		problems []string
+	}{
+		{
+			name:     "schema problems are listed instead of the parse error",
+			resp:     `{"a": "one"}`,
+			parseErr: errors.New("parse failed"),
+			problems: []string{"$: missing required property b", "$.a: expected type integer but got string"},
+		},
+		{
+			name:     "parse error when the schema matches",
+			resp:     `{"a": 1, "b": 2}`,
+			parseErr: errors.New(`question "a" has 1 answers, but needs exactly 2`),
+			problems: []string{`question "a" has 1 answers, but needs exactly 2`},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prompt := repairPrompt(tc.resp, schema, tc.parseErr)
+			for _, problem := range tc.problems {
+				if !strings.Contains(prompt, problem) {
+					t.Errorf("expected the prompt to list %q, got\n%s", problem, prompt)
+				}
+			}
+			if len(tc.problems) > 1 && strings.Contains(prompt, tc.parseErr.Error()) {
+				t.Errorf("expected the parse error to be replaced by the schema problems, got\n%s", prompt)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package docqa
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// passageSeparator is placed between passages that are not next to each other in the document.
+const passageSeparator = "\n\n[...]\n\n"
+
+// ExtractAnswersRetrieved is the same as [ExtractAnswersContext], but each question only sees the passages of the document that are relevant to it.
+// This suits large documents where each question only needs a few paragraphs, and needs no embedding service.
+//
+// The document is split with [ChunkDocument] into passages of at most maxPassageBytes, which are indexed with a [BM25Index].
+// Each question picks the passagesPerQuestion passages that best match its key, [Question.Question], and [Question.Details].
+// Questions that share a passage are answered in the same call, as long as their passages fit within [WithMaxPassagesPerCall].
+// Each call is sent its passages in document order, with a `[...]` marker wherever text was skipped.
+//
+// Ranges in the [EntityAttributes] are mapped back to be relative to the whole document.
+// The returned usage is the sum of the usage of every call.
+func ExtractAnswersRetrieved(ctx context.Context, client Client, qa Protocol, questions map[string]Question, documentText string, maxPassageBytes int, passagesPerQuestion int, opts ...ExtractOption) (map[string][]Entity, LLMUsage, error) {
+	if passagesPerQuestion <= 0 {
+		return nil, LLMUsage{}, fmt.Errorf("passages per question must be positive, got %d", passagesPerQuestion)
+	}
+	if err := validateQuestions(questions); err != nil {
+		return nil, LLMUsage{}, err
+	}
+	cfg := newExtractConfig(opts)
+	maxPassages := max(cfg.maxPassages, passagesPerQuestion)
+	chunks := ChunkDocument(documentText, maxPassageBytes, 0)
+	index := NewBM25Index(chunks)
+
+	type questionGroup struct {
+		questions map[string]Question
+		passages  []int
+	}
+	groups := make([]*questionGroup, 0)
+	for _, key := range sortedKeys(questions) {
+		q := questions[key]
+		query := strings.Join(append([]string{key, q.Question}, q.Details...), " ")
+		passages := make([]int, 0, passagesPerQuestion)
+		for _, result := range index.Search(query, passagesPerQuestion) {
+			passages = append(passages, result.Index)
+		}
+		slices.Sort(passages)
+		joined := false
+		for _, g := range groups {
+			union := append(slices.Clone(g.passages), passages...)
+			slices.Sort(union)
+			union = slices.Compact(union)
+			shared := len(union) < len(g.passages)+len(passages)
+			if shared && len(union) <= maxPassages {
+				g.questions[key] = q
+				g.passages = union
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			groups = append(groups, &questionGroup{questions: map[string]Question{key: q}, passages: passages})
+		}
+	}
+
+	calls := make([]extractCall, len(groups))
+	texts := make([]passageText, len(groups))
+	for i, g := range groups {
+		texts[i] = joinPassages(chunks, g.passages)
+		calls[i] = extractCall{questions: g.questions, text: texts[i].text}
+	}
+	results, usage, failed, err := extractAll(ctx, client, qa, calls, opts)
+	if err != nil {
+		return nil, usage, fmt.Errorf("call for questions %s failed: %w", strings.Join(sortedKeys(groups[failed].questions), ", "), err)
+	}
+
+	answers := make(map[string][]Entity, len(questions))
+	for key := range questions {
+		answers[key] = []Entity{}
+	}
+	for i, result := range results {
+		mapAnswerRanges(result, texts[i].toDocument)
+		// A provider that does not follow the schema strictly may answer another group's questions too,
+		// so only the questions the call was for are kept.
+		for key := range groups[i].questions {
+			if entities, ok := result[key]; ok {
+				answers[key] = entities
+			}
+		}
+	}
+	return answers, usage, nil
+}
+
+// passageSegment records where a passage is in the text sent to the LLM, and where it is in the document.
+type passageSegment struct {
+	textStart int
+	docStart  int
+	length    int
+}
+
+// passageText is the text made by joining passages of a document, which remembers where each passage came from.
+type passageText struct {
+	text     string
+	segments []passageSegment
+}
+
+// joinPassages joins the chunks with the given sorted indices, separating those that are not next to each other in the document.
+func joinPassages(chunks []Chunk, indices []int) passageText {
+	var sb strings.Builder
+	segments := make([]passageSegment, 0, len(indices))
+	for i, idx := range indices {
+		chunk := chunks[idx]
+		if i > 0 && indices[i-1]+1 != idx {
+			sb.WriteString(passageSeparator)
+		}
+		segments = append(segments, passageSegment{textStart: sb.Len(), docStart: chunk.Start, length: len(chunk.Text)})
+		sb.WriteString(chunk.Text)
+	}
+	return passageText{text: sb.String(), segments: segments}
+}
+
+// toDocument converts a range of the joined text into a range of the document.
+// An end that falls in a separator is moved back to the end of the passage before it,
+// and a start that falls in a separator is moved forward to the start of the passage after it.
+func (p passageText) toDocument(r Range) Range {
+	if len(p.segments) == 0 {
+		return r
+	}
+	last := p.segments[len(p.segments)-1]
+	start := last.docStart + last.length
+	for _, s := range p.segments {
+		if r.Start < s.textStart+s.length {
+			start = s.docStart + max(r.Start-s.textStart, 0)
+			break
+		}
+	}
+	end := p.segments[0].docStart
+	for _, s := range slices.Backward(p.segments) {
+		if r.End > s.textStart {
+			end = s.docStart + min(r.End-s.textStart, s.length)
+			break
+		}
+	}
+	return Range{start, max(start, end)}
+}
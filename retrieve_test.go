@@ -0,0 +1,167 @@
+package docqa
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPassageTextToDocument(t *testing.T) {
+	doc := "aaaa bbbb cccc dddd"
+	chunks := []Chunk{{"aaaa ", 0}, {"bbbb ", 5}, {"cccc ", 10}, {"dddd", 15}}
+	p := joinPassages(chunks, []int{0, 2, 3})
+	// "aaaa " is at 0-5 in the text, the separator at 5-14, "cccc " at 14-19, and "dddd" at 19-23.
+	if want := "aaaa " + passageSeparator + "cccc dddd"; p.text != want {
+		t.Fatalf("expected joined text %q, got %q", want, p.text)
+	}
+	cases := []struct {
+		name  string
+		r     Range
+		want  Range
+		quote string
+	}{
+		{name: "within the first passage", r: Range{1, 3}, want: Range{1, 3}, quote: "aa"},
+		{name: "within a later passage", r: Range{15, 18}, want: Range{11, 14}, quote: "ccc"},
+		{name: "across adjacent passages", r: Range{16, 21}, want: Range{12, 17}, quote: "cc dd"},
+		{name: "across the separator", r: Range{2, 16}, want: Range{2, 12}, quote: "aa bbbb cc"},
+		{name: "start in the separator", r: Range{7, 17}, want: Range{10, 13}, quote: "ccc"},
+		{name: "end in the separator", r: Range{1, 8}, want: Range{1, 5}, quote: "aaa "},
+		{name: "entirely in the separator", r: Range{6, 8}, want: Range{10, 10}, quote: ""},
+		{name: "past the end", r: Range{25, 30}, want: Range{19, 19}, quote: ""},
+		{name: "whole text", r: Range{0, 23}, want: Range{0, 19}, quote: doc},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := p.toDocument(tc.r)
+			if got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			if doc[got.Start:got.End] != tc.quote {
+				t.Errorf("expected the range to cover %q, got %q", tc.quote, doc[got.Start:got.End])
+			}
+		})
+	}
+
+	t.Run("no passages", func(t *testing.T) {
+		r := Range{3, 7}
+		if got := joinPassages(chunks, nil).toDocument(r); got != r {
+			t.Errorf("expected the range to be unchanged, got %v", got)
+		}
+	})
+}
+
+func TestExtractAnswersRetrieved(t *testing.T) {
+	passages := []string{"The buyer is Acme Ltd.", "The seller is Bolt plc.", "The price is ten pounds.", "Delivery is due in March."}
+	doc := strings.Join(passages, "\n\n")
+	text := []string{"text"}
+	questions := map[string]Question{
+		"buyer":         {Question: "Who is the buyer?", AllowedTypeKeys: text},
+		"buyer_company": {Question: "Which company is the buyer?", AllowedTypeKeys: text},
+		"seller":        {Question: "Who is the seller?", AllowedTypeKeys: text},
+		"price":         {Question: "What is the price?", AllowedTypeKeys: text},
+		"delivery":      {Question: "When is delivery due?", AllowedTypeKeys: text},
+	}
+	questionHeading := regexp.MustCompile("(?m)^# `(\\w+)`$")
+	cases := []struct {
+		name                string
+		passagesPerQuestion int
+		opts                []ExtractOption
+		// groups are the questions answered by each call, and passages the passages each call was sent.
+		groups   [][]string
+		passages [][]int
+	}{
+		{
+			name:                "questions sharing a passage are grouped",
+			passagesPerQuestion: 1,
+			groups:              [][]string{{"buyer", "buyer_company"}, {"delivery"}, {"price"}, {"seller"}},
+			passages:            [][]int{{0}, {3}, {2}, {1}},
+		},
+		{
+			name:                "groups are limited to the passages per question",
+			passagesPerQuestion: 2,
+			groups:              [][]string{{"buyer", "buyer_company", "seller"}, {"delivery"}, {"price"}},
+			passages:            [][]int{{0, 1}, {0, 3}, {0, 2}},
+		},
+		{
+			name:                "larger calls",
+			passagesPerQuestion: 2,
+			opts:                []ExtractOption{WithMaxPassagesPerCall(4)},
+			groups:              [][]string{{"buyer", "buyer_company", "delivery", "price", "seller"}},
+			passages:            [][]int{{0, 1, 2, 3}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			type call struct {
+				group  []string
+				prompt string
+			}
+			var mu sync.Mutex
+			var calls []call
+			client := &stubClient{respond: func(_ int, systemPrompt string, messages []Message) (string, LLMUsage, error) {
+				own := make([]string, 0)
+				for _, m := range questionHeading.FindAllStringSubmatch(systemPrompt, -1) {
+					own = append(own, m[1])
+				}
+				mu.Lock()
+				calls = append(calls, call{group: own, prompt: messages[0].Content})
+				mu.Unlock()
+				// Answer every question, not just those asked, as a provider that ignores the schema might.
+				answers := make([]string, 0, len(questions))
+				for _, key := range sortedKeys(questions) {
+					answers = append(answers, fmt.Sprintf(`%q:[{"answer_type":"text","text":%q}]`, key, strings.Join(own, "+")))
+				}
+				return "{" + strings.Join(answers, ",") + "}", LLMUsage{InputTokens: 1}, nil
+			}}
+			answers, usage, err := ExtractAnswersRetrieved(context.Background(), client, newTestTextProtocol(), questions, doc, 30, tc.passagesPerQuestion, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The calls are made concurrently, so put them in the order of their first question.
+			slices.SortFunc(calls, func(a, b call) int { return strings.Compare(a.group[0], b.group[0]) })
+			groups := make([][]string, len(calls))
+			for i, c := range calls {
+				groups[i] = c.group
+			}
+			if !reflect.DeepEqual(groups, tc.groups) {
+				t.Fatalf("expected calls for %v, got %v", tc.groups, groups)
+			}
+			if usage.InputTokens != len(tc.groups) {
+				t.Errorf("expected the usage of %d calls, got %+v", len(tc.groups), usage)
+			}
+			for i, c := range calls {
+				for j, passage := range passages {
+					if sent := strings.Contains(c.prompt, passage); sent != slices.Contains(tc.passages[i], j) {
+						t.Errorf("call %d: expected passage %d to be sent to be %v, got prompt %q", i, j, !sent, c.prompt)
+					}
+				}
+			}
+			// Each question is answered by the call it was asked in, even though every call answered it.
+			for _, group := range tc.groups {
+				for _, key := range group {
+					got := answers[key]
+					if len(got) != 1 || got[0].(*testTextEntity).Text != strings.Join(group, "+") {
+						t.Errorf("expected %s to be answered by the call for %v, got %v", key, group, got)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("passages per question must be positive", func(t *testing.T) {
+		client := stubResponses(LLMUsage{})
+		for _, n := range []int{0, -1} {
+			if _, _, err := ExtractAnswersRetrieved(context.Background(), client, newTestTextProtocol(), questions, doc, 30, n); err == nil {
+				t.Errorf("expected %d passages per question to be rejected", n)
+			}
+		}
+		if len(client.calls) != 0 {
+			t.Errorf("expected no calls, got %d", len(client.calls))
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package docqa
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveSchemaRef looks up a local json pointer reference, such as `#/definitions/name`, within the root schema.
+func resolveSchemaRef(root map[string]any, ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local schema references are supported, got %s", ref)
+	}
+	var node any = root
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("could not resolve schema reference %s", ref)
+		}
+		node, ok = m[part]
+		if !ok {
+			return nil, fmt.Errorf("could not resolve schema reference %s", ref)
+		}
+	}
+	resolved, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema reference %s does not point to a schema", ref)
+	}
+	return resolved, nil
+}
+
+// schemaTypes returns the allowed types of a schema node, which may be a single type or a list of types.
+func schemaTypes(node map[string]any) []string {
+	switch t := node["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	case []string:
+		return t
+	default:
+		return nil
+	}
+}
+
+// schemaList returns a list of sub-schemas, such as the options of an `anyOf`.
+func schemaList(node map[string]any, key string) []map[string]any {
+	switch l := node[key].(type) {
+	case []any:
+		schemas := make([]map[string]any, 0, len(l))
+		for _, v := range l {
+			if s, ok := v.(map[string]any); ok {
+				schemas = append(schemas, s)
+			}
+		}
+		return schemas
+	case []map[string]any:
+		return l
+	default:
+		return nil
+	}
+}
+
+// schemaStrings returns a list of strings from a schema node, such as the `required` keys.
+func schemaStrings(node map[string]any, key string) []string {
+	switch l := node[key].(type) {
+	case []any:
+		strs := make([]string, 0, len(l))
+		for _, v := range l {
+			if s, ok := v.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	case []string:
+		return l
+	default:
+		return nil
+	}
+}
+
+// schemaInt reads an integer keyword (such as `minItems`), which may have been decoded from json as a float.
+func schemaInt(node map[string]any, key string) (int, bool) {
+	switch v := node[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// schemaEnum returns the allowed values of an `enum`.
+func schemaEnum(node map[string]any) []any {
+	switch l := node["enum"].(type) {
+	case []any:
+		return l
+	case []string:
+		vals := make([]any, len(l))
+		for i, v := range l {
+			vals[i] = v
+		}
+		return vals
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,164 @@
+package docqa
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SchemaTransform rewrites a json schema into an equivalent (or close) schema, for example to suit the schema dialect of a provider.
+// Transforms never modify the schema they are given.
+type SchemaTransform func(schema map[string]any) (map[string]any, error)
+
+// ChainSchemaTransforms builds a [SchemaTransform] that applies each of the transforms in order.
+func ChainSchemaTransforms(transforms ...SchemaTransform) SchemaTransform {
+	return func(schema map[string]any) (map[string]any, error) {
+		var err error
+		for _, t := range transforms {
+			schema, err = t(schema)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return schema, nil
+	}
+}
+
+// InlineSchemaRefs builds a [SchemaTransform] that replaces every local `$ref` with a copy of the schema it points to,
+// then removes the `definitions` and `$defs` that are no longer needed. Recursive schemas cannot be inlined, and cause an error.
+func InlineSchemaRefs() SchemaTransform {
+	return func(schema map[string]any) (map[string]any, error) {
+		inlined, err := inlineSchemaRefs(schema, schema, nil)
+		if err != nil {
+			return nil, err
+		}
+		delete(inlined, "definitions")
+		delete(inlined, "$defs")
+		return inlined, nil
+	}
+}
+
+func inlineSchemaRefs(node, root map[string]any, visiting []string) (map[string]any, error) {
+	if ref, ok := node["$ref"].(string); ok {
+		if slices.Contains(visiting, ref) {
+			return nil, fmt.Errorf("cannot inline recursive schema reference %s", ref)
+		}
+		resolved, err := resolveSchemaRef(root, ref)
+		if err != nil {
+			return nil, err
+		}
+		return inlineSchemaRefs(resolved, root, append(visiting, ref))
+	}
+	return mapSubSchemas(node, func(sub map[string]any) (map[string]any, error) {
+		return inlineSchemaRefs(sub, root, visiting)
+	})
+}
+
+// ConstToEnum builds a [SchemaTransform] that replaces every `const` with an `enum` with a single value.
+func ConstToEnum() SchemaTransform {
+	return transformEachSchema(func(node map[string]any) (map[string]any, error) {
+		if c, ok := node["const"]; ok {
+			delete(node, "const")
+			node["enum"] = []any{c}
+		}
+		return node, nil
+	})
+}
+
+// StripSchemaKeywords builds a [SchemaTransform] that removes the given keywords from every schema node.
+// Property names are never affected, only keywords.
+func StripSchemaKeywords(keywords ...string) SchemaTransform {
+	return transformEachSchema(func(node map[string]any) (map[string]any, error) {
+		for _, k := range keywords {
+			delete(node, k)
+		}
+		return node, nil
+	})
+}
+
+// UppercaseSchemaTypes builds a [SchemaTransform] that converts `type` names to upper case, as used by OpenAPI style schemas.
+func UppercaseSchemaTypes() SchemaTransform {
+	return transformEachSchema(func(node map[string]any) (map[string]any, error) {
+		if t, ok := node["type"].(string); ok {
+			node["type"] = strings.ToUpper(t)
+		}
+		return node, nil
+	})
+}
+
+// GeminiSchemaTransform builds the [SchemaTransform] needed to turn the schemas emitted by [Protocol]s into
+// a `responseSchema` that Gemini accepts.
+func GeminiSchemaTransform() SchemaTransform {
+	return ChainSchemaTransforms(
+		InlineSchemaRefs(),
+		ConstToEnum(),
+		StripSchemaKeywords("additionalProperties", "$schema", "$id", "title", "definitions", "$defs"),
+		UppercaseSchemaTypes(),
+	)
+}
+
+// transformEachSchema builds a [SchemaTransform] that applies f to a copy of every schema node, from the leaves up.
+func transformEachSchema(f func(node map[string]any) (map[string]any, error)) SchemaTransform {
+	var walk func(node map[string]any) (map[string]any, error)
+	walk = func(node map[string]any) (map[string]any, error) {
+		mapped, err := mapSubSchemas(node, walk)
+		if err != nil {
+			return nil, err
+		}
+		return f(mapped)
+	}
+	return walk
+}
+
+// mapSubSchemas shallow copies a schema node, replacing each of its direct sub-schemas with the result of f.
+func mapSubSchemas(node map[string]any, f func(map[string]any) (map[string]any, error)) (map[string]any, error) {
+	out := make(map[string]any, len(node))
+	for k, v := range node {
+		out[k] = v
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := node[key].(map[string]any); ok {
+			mapped, err := f(sub)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = mapped
+		}
+	}
+	for _, key := range []string{"properties", "definitions", "$defs"} {
+		subs, ok := node[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		mappedSubs := make(map[string]any, len(subs))
+		for name, v := range subs {
+			sub, ok := v.(map[string]any)
+			if !ok {
+				mappedSubs[name] = v
+				continue
+			}
+			mapped, err := f(sub)
+			if err != nil {
+				return nil, err
+			}
+			mappedSubs[name] = mapped
+		}
+		out[key] = mappedSubs
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		subs := schemaList(node, key)
+		if subs == nil {
+			continue
+		}
+		mappedSubs := make([]any, len(subs))
+		for i, sub := range subs {
+			mapped, err := f(sub)
+			if err != nil {
+				return nil, err
+			}
+			mappedSubs[i] = mapped
+		}
+		out[key] = mappedSubs
+	}
+	return out, nil
+}
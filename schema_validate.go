@@ -0,0 +1,235 @@
+package docqa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaValidationError lists every way in which a response did not match its schema.
+type SchemaValidationError struct {
+	Problems []string
+}
+
+// Error implements error.
+func (e *SchemaValidationError) Error() string {
+	return fmt.Sprintf("response did not match schema: %s", strings.Join(e.Problems, "; "))
+}
+
+// ValidateJSON checks that resp is json which matches the schema, returning a [*SchemaValidationError] if it does not.
+// It supports the same subset of json schema as [NewFakeClient], which covers everything [Protocol]s in this package emit.
+func ValidateJSON(resp string, schema map[string]any) error {
+	dec := json.NewDecoder(bytes.NewBufferString(resp))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return &SchemaValidationError{Problems: []string{fmt.Sprintf("response was not valid json: %v", err)}}
+	}
+	v := &schemaValidator{root: schema}
+	v.validate(value, schema, "$")
+	if len(v.problems) > 0 {
+		return &SchemaValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	root     map[string]any
+	problems []string
+}
+
+func (v *schemaValidator) fail(path string, format string, args ...any) {
+	v.problems = append(v.problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// check validates into a scratch validator, returning the problems without recording them.
+func (v *schemaValidator) check(value any, node map[string]any, path string) []string {
+	scratch := &schemaValidator{root: v.root}
+	scratch.validate(value, node, path)
+	return scratch.problems
+}
+
+func (v *schemaValidator) validate(value any, node map[string]any, path string) {
+	if ref, ok := node["$ref"].(string); ok {
+		resolved, err := resolveSchemaRef(v.root, ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.validate(value, resolved, path)
+		return
+	}
+	if c, ok := node["const"]; ok && !jsonEqual(value, c) {
+		v.fail(path, "expected %v but got %v", c, value)
+		return
+	}
+	if enum := schemaEnum(node); len(enum) > 0 {
+		found := false
+		for _, e := range enum {
+			found = found || jsonEqual(value, e)
+		}
+		if !found {
+			v.fail(path, "%v is not one of %v", value, enum)
+			return
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if options := schemaList(node, key); len(options) > 0 {
+			// If nothing matches, report the problems of the closest option, which is most likely the intended one.
+			var closest []string
+			for i, option := range options {
+				problems := v.check(value, option, path)
+				if len(problems) == 0 {
+					closest = nil
+					break
+				}
+				if i == 0 || len(problems) < len(closest) {
+					closest = problems
+				}
+			}
+			if closest != nil {
+				v.fail(path, "did not match any of the allowed options")
+				v.problems = append(v.problems, closest...)
+				return
+			}
+		}
+	}
+	if types := schemaTypes(node); len(types) > 0 {
+		matchedType := ""
+		for _, t := range types {
+			if jsonHasType(value, t) {
+				matchedType = t
+				break
+			}
+		}
+		if matchedType == "" {
+			v.fail(path, "expected type %s but got %s", strings.Join(types, " or "), jsonTypeName(value))
+			return
+		}
+	}
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(val, node, path)
+	case []any:
+		v.validateArray(val, node, path)
+	case string:
+		if format, _ := node["format"].(string); format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, val); err != nil {
+				v.fail(path, "%q is not an RFC 3339 date-time, such as 2006-01-02T15:04:05Z", val)
+			}
+		}
+	case json.Number:
+		f, _ := val.Float64()
+		if lo, ok := schemaInt(node, "minimum"); ok && f < float64(lo) {
+			v.fail(path, "%v is less than the minimum %d", val, lo)
+		}
+		if hi, ok := schemaInt(node, "maximum"); ok && f > float64(hi) {
+			v.fail(path, "%v is more than the maximum %d", val, hi)
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(obj map[string]any, node map[string]any, path string) {
+	props, _ := node["properties"].(map[string]any)
+	for _, k := range schemaStrings(node, "required") {
+		if _, ok := obj[k]; !ok {
+			v.fail(path, "missing required property %s", k)
+		}
+	}
+	for _, k := range sortedKeys(obj) {
+		propSchema, ok := props[k].(map[string]any)
+		if !ok {
+			if additional, ok := node["additionalProperties"].(bool); ok && !additional {
+				v.fail(path, "unexpected property %s", k)
+			}
+			continue
+		}
+		v.validate(obj[k], propSchema, fmt.Sprintf("%s.%s", path, k))
+	}
+}
+
+func (v *schemaValidator) validateArray(arr []any, node map[string]any, path string) {
+	if lo, ok := schemaInt(node, "minItems"); ok && len(arr) < lo {
+		v.fail(path, "expected at least %d items but got %d", lo, len(arr))
+	}
+	if hi, ok := schemaInt(node, "maxItems"); ok && len(arr) > hi {
+		v.fail(path, "expected at most %d items but got %d", hi, len(arr))
+	}
+	items, ok := node["items"].(map[string]any)
+	if !ok {
+		return
+	}
+	for i, item := range arr {
+		v.validate(item, items, fmt.Sprintf("%s[%d]", path, i))
+	}
+}
+
+func jsonHasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	default:
+		return false
+	}
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// jsonEqual compares two values as json, so that numbers of different go types can be equal.
+func jsonEqual(a, b any) bool {
+	normalise := func(x any) any {
+		bs, err := json.Marshal(x)
+		if err != nil {
+			return x
+		}
+		var out any
+		if err := json.Unmarshal(bs, &out); err != nil {
+			return x
+		}
+		return out
+	}
+	return reflect.DeepEqual(normalise(a), normalise(b))
+}
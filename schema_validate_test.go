@@ -0,0 +1,147 @@
+package docqa
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateJSON(t *testing.T) {
+	answerSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"answer_type": map[string]any{"const": "name"},
+			"name":        map[string]any{"type": "string"},
+			"age":         map[string]any{"type": "integer", "minimum": 0, "maximum": 150},
+		},
+		"required":             []any{"answer_type", "name"},
+		"additionalProperties": false,
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"people": map[string]any{
+				"type":     "array",
+				"items":    map[string]any{"$ref": "#/$defs/person"},
+				"minItems": 1,
+				"maxItems": 2,
+			},
+		},
+		"required": []any{"people"},
+		"$defs":    map[string]any{"person": answerSchema},
+	}
+	cases := []struct {
+		name     string
+		schema   map[string]any
+		resp     string
+		problems []string
+	}{
+		{
+			name:   "valid",
+			schema: schema,
+			resp:   `{"people": [{"answer_type": "name", "name": "Ada", "age": 36}]}`,
+		},
+		{
+			name:     "not json",
+			schema:   schema,
+			resp:     `{"people": [`,
+			problems: []string{"response was not valid json: unexpected EOF"},
+		},
+		{
+			name:     "missing required property",
+			schema:   schema,
+			resp:     `{}`,
+			problems: []string{"$: missing required property people"},
+		},
+		{
+			name:   "problems in refs are reported with their path",
+			schema: schema,
+			resp:   `{"people": [{"answer_type": "name", "name": "Ada"}, {"answer_type": "date", "name": 1, "age": 1.5, "extra": true}]}`,
+			problems: []string{
+				"$.people[1].age: expected type integer but got number",
+				"$.people[1].answer_type: expected name but got date",
+				"$.people[1]: unexpected property extra",
+				"$.people[1].name: expected type string but got number",
+			},
+		},
+		{
+			name:     "too few items",
+			schema:   schema,
+			resp:     `{"people": []}`,
+			problems: []string{"$.people: expected at least 1 items but got 0"},
+		},
+		{
+			name:     "too many items",
+			schema:   schema,
+			resp:     `{"people": [{"answer_type": "name", "name": "A"}, {"answer_type": "name", "name": "B"}, {"answer_type": "name", "name": "C"}]}`,
+			problems: []string{"$.people: expected at most 2 items but got 3"},
+		},
+		{
+			name:     "number out of range",
+			schema:   schema,
+			resp:     `{"people": [{"answer_type": "name", "name": "Ada", "age": 200}]}`,
+			problems: []string{"$.people[0].age: 200 is more than the maximum 150"},
+		},
+		{
+			name:     "enum",
+			schema:   map[string]any{"enum": []any{"a", 1}},
+			resp:     `"b"`,
+			problems: []string{"$: b is not one of [a 1]"},
+		},
+		{
+			name:   "enum matches numbers as json",
+			schema: map[string]any{"enum": []any{"a", 1}},
+			resp:   `1.0`,
+		},
+		{
+			name:   "nullable type",
+			schema: map[string]any{"type": []any{"string", "null"}},
+			resp:   `null`,
+		},
+		{
+			name: "closest option of any of",
+			schema: map[string]any{"anyOf": []any{
+				map[string]any{"type": "object", "required": []any{"c", "d"}},
+				map[string]any{"type": "object", "properties": map[string]any{"a": map[string]any{"type": "string"}, "b": map[string]any{"type": "string"}}, "required": []any{"a", "b"}},
+			}},
+			resp: `{"a": "x"}`,
+			problems: []string{
+				"$: did not match any of the allowed options",
+				"$: missing required property b",
+			},
+		},
+		{
+			name:   "date-time format",
+			schema: map[string]any{"type": "array", "items": map[string]any{"type": "string", "format": "date-time"}},
+			resp:   `["2024-02-29T12:00:00Z", "2024-02-30"]`,
+			problems: []string{
+				`$[1]: "2024-02-30" is not an RFC 3339 date-time, such as 2006-01-02T15:04:05Z`,
+			},
+		},
+		{
+			name:     "unresolvable ref",
+			schema:   map[string]any{"$ref": "#/$defs/missing"},
+			resp:     `{}`,
+			problems: []string{"$: could not resolve schema reference #/$defs/missing"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJSON(tc.resp, tc.schema)
+			if tc.problems == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var validationErr *SchemaValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a SchemaValidationError, got %v", err)
+			}
+			if !reflect.DeepEqual(validationErr.Problems, tc.problems) {
+				t.Errorf("expected problems\n%s\ngot\n%s", strings.Join(tc.problems, "\n"), strings.Join(validationErr.Problems, "\n"))
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package docqa
 
+import "slices"
+
 // Wrap a standard json schema into one that can be sent to openai
 func wrapOpenAISchema(schema map[string]any) map[string]any {
 	return map[string]any{
@@ -11,3 +13,22 @@ func wrapOpenAISchema(schema map[string]any) map[string]any {
 		},
 	}
 }
+
+// sortedKeys returns the keys of a map in sorted order, so that iterating it is deterministic.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// truncateForError shortens long strings, such as prompts, so they can be included in error messages.
+func truncateForError(s string) string {
+	const maxLen = 200
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}